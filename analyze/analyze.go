@@ -22,79 +22,227 @@ var (
 )
 
 const (
-	WarnFormatBrackets       = "bracket structure doesn't match"
-	WarnFormatWrongFormat    = "comment '%v' doesn't match the required definition format"
-	WarnAttributeWrongFormat = "attributes '%v' don't match fully the required attribute format"
+	WarnFormatBrackets          = "bracket structure doesn't match"
+	WarnFormatWrongFormat       = "comment '%v' doesn't match the required definition format"
+	WarnAttributeWrongFormat    = "attributes '%v' don't match fully the required attribute format"
+	WarnAttributeDuplicateKey   = "attribute '%v' is defined more than once in '%v'"
+	WarnDefinitionNotRepeatable = "annotation '%v' is not repeatable but defined %v times"
 
 	TrueString = "TRUE"
+
+	// SourceDoc marks definitions read from the documentation of a spec
+	SourceDoc = "doc"
+	// SourceLine marks definitions read from the trailing line comment of a spec
+	SourceLine = "line"
 )
 
 type Definition struct {
 	Identifier string            `json:"identifier"`
 	Arguments  map[string]string `json:"arguments"`
+	Source     string            `json:"source,omitempty"`
+	Position   Position          `json:"-"`
 }
 
 type DefinitionList []Definition
 
-type Warning string
-
-type Warnings []string
-
 type Spec interface {
 	Doc() []string
 }
 
+// Locatable is implemented by specs which know where they are declared, and on which lines their documentation and
+// trailing line comment are located; it is used to position diagnostics
+type Locatable interface {
+	Location() (string, int)
+	CommentLocations() ([]int, []int)
+}
+
+// LineSpec is implemented by specs which, besides their documentation, carry a trailing line comment
+// (e.g. `Name string // crud.field{name="name"}`)
+type LineSpec interface {
+	Spec
+	LineDoc() []string
+}
+
+// Cardinality declares how often an annotation may be defined on a single spec
+type Cardinality int
+
+const (
+	// Repeatable annotations may be defined any number of times on a spec (default)
+	Repeatable Cardinality = iota
+	// Unique annotations may only be defined once on a spec
+	Unique
+)
+
+// Cardinalities maps annotation identifiers to their cardinality; identifiers not listed are repeatable, though a
+// registered schema makes its annotation unique unless the schema is Repeatable (see Schema.Repeatable)
+type Cardinalities map[string]Cardinality
+
+// Analyzer holds the configuration used when extracting definitions from specs
+// the zero value extracts every comment without filtering and treats all annotations as repeatable
+type Analyzer struct {
+	// Filter is called with the information whether a comment matches the definition form; if it returns false
+	// the comment is skipped
+	Filter *func(bool) bool
+
+	// Cardinalities declares annotations which may only be defined once on a spec
+	Cardinalities Cardinalities
+
+	// Marker is a prefix required in front of every annotation (e.g. "@" for `@route{}` or "+ann:" for
+	// `+ann:route{}`); if set, comments without the marker are skipped and comments with the marker are always
+	// considered annotations, so the filter is not applied
+	Marker string
+
+	// Namespaces is an allow-list of annotation namespaces (e.g. "crud" allows "crud.model" and "crud.field");
+	// if set, definitions outside of the listed namespaces are skipped
+	Namespaces []string
+
+	// Grammar is used to parse the annotations; if not set GrammarV1 is used
+	Grammar Grammar
+}
+
+// grammar returns the grammar configured or GrammarV1 as default
+func (a Analyzer) grammar() Grammar {
+	if a.Grammar.Definition == nil || a.Grammar.Argument == nil {
+		return GrammarV1
+	}
+	return a.Grammar
+}
+
+// allowed checks if an identifier is part of the namespaces allowed by the analyzer
+func (a Analyzer) allowed(identifier string) bool {
+	if len(a.Namespaces) == 0 {
+		return true
+	}
+
+	for _, ns := range a.Namespaces {
+		if identifier == ns || strings.HasPrefix(identifier, ns+".") {
+			return true
+		}
+	}
+	return false
+}
+
 // ExtractDefinitionsOnSpec extracts protocol matching annotations from a functions documentation
 // this is used to build a structure of annotations assigned to functions
-func ExtractDefinitionsOnSpec(s Spec, filter *func(bool) bool) (DefinitionList, Warnings) {
-	var dlMu sync.Mutex
+func ExtractDefinitionsOnSpec(s Spec, filter *func(bool) bool) (DefinitionList, Diagnostics) {
+	return Analyzer{Filter: filter}.ExtractDefinitions(s)
+}
+
+// comment is a single comment line of a spec alongside the source it was read from and its position
+type comment struct {
+	text     string
+	source   string
+	position Position
+}
+
+// comments returns all comment lines of a spec, documentation first, followed by the trailing line comment
+func comments(s Spec) []comment {
+	var file string
+	var docLines, lineDocLines []int
+	if l, ok := s.(Locatable); ok {
+		file, _ = l.Location()
+		docLines, lineDocLines = l.CommentLocations()
+	}
+
+	position := func(lines []int, idx int) Position {
+		p := Position{File: file}
+		if idx < len(lines) {
+			p.Line = lines[idx]
+		}
+		return p
+	}
+
+	var cs []comment
+	for idx, c := range s.Doc() {
+		cs = append(cs, comment{text: c, source: SourceDoc, position: position(docLines, idx)})
+	}
+	if ls, ok := s.(LineSpec); ok {
+		for idx, c := range ls.LineDoc() {
+			cs = append(cs, comment{text: c, source: SourceLine, position: position(lineDocLines, idx)})
+		}
+	}
+	return cs
+}
+
+// ExtractDefinitions extracts protocol matching annotations from the documentation and trailing line comment of a
+// spec, using the configuration of the analyzer; definitions are returned in the order of the comment lines
+func (a Analyzer) ExtractDefinitions(s Spec) (DefinitionList, Diagnostics) {
 	var dl DefinitionList
-	if s.Doc() == nil {
+	cs := comments(s)
+	if cs == nil {
 		return dl, nil
 	}
 
-	var warnMu sync.Mutex
-	var warnings []string
+	g := a.grammar()
+	defs := make([]*Definition, len(cs))
+	reported := make([]*Diagnostic, len(cs))
 
 	var wg sync.WaitGroup
-	wg.Add(len(s.Doc()))
-	for _, c := range s.Doc() {
-		go func(c string) {
+	wg.Add(len(cs))
+	for idx, cm := range cs {
+		go func(idx int, cm comment) {
 			defer wg.Done()
 
+			c := cm.text
+			report := func(d Diagnostic) {
+				reported[idx] = &d
+			}
+
+			if a.Marker != "" {
+				if !strings.HasPrefix(c, a.Marker) {
+					return // skip comments without marker
+				}
+				c = strings.TrimPrefix(c, a.Marker)
+			}
+
 			nbrackets := len(BracketRe.FindAllStringSubmatch(c, -1))
 			if (nbrackets % 2) != 0 {
-				warnMu.Lock()
-				warnings = append(warnings, WarnFormatBrackets)
-				warnMu.Unlock()
+				report(Diagnostic{
+					Severity:     SeverityWarning,
+					Code:         CodeFormatBrackets,
+					Message:      WarnFormatBrackets,
+					Position:     cm.position,
+					SuggestedFix: "balance the curly brackets of the comment",
+				})
 				return
 			}
 
-			if filter != nil {
-				if (*filter)(DefinitionRe.MatchString(c)) == false {
+			if a.Filter != nil && a.Marker == "" {
+				if (*a.Filter)(g.Definition.MatchString(c)) == false {
 					return // skip filtered comments
 				}
 			}
 
-			def := Definition{}
-			m := DefinitionRe.FindStringSubmatch(c)
+			def := Definition{
+				Source:   cm.source,
+				Position: cm.position,
+			}
+			m := g.Definition.FindStringSubmatch(c)
 
 			if len(m) < 3 {
-				warnMu.Lock()
-				warnings = append(warnings, fmt.Sprintf(WarnFormatWrongFormat, c))
-				warnMu.Unlock()
+				report(Diagnostic{
+					Severity:     SeverityWarning,
+					Code:         CodeFormatWrongFormat,
+					Message:      fmt.Sprintf(WarnFormatWrongFormat, c),
+					Position:     cm.position,
+					SuggestedFix: "use the form identifier{attributes}",
+				})
 				return
 			}
 
 			def.Identifier = m[1]
+			if !a.allowed(def.Identifier) {
+				return // skip definitions outside of allowed namespaces
+			}
+
 			def.Arguments = map[string]string{}
 
 			attributeString := m[2]
-			matches := ArgumentRe.FindAllStringSubmatch(m[2], -1)
+			matches := g.Argument.FindAllStringSubmatch(m[2], -1)
 			for _, m := range matches {
 				attributeString = strings.Replace(attributeString, m[0], "", 1) // remove matched part
 
-				key := m[ArgumentRe.SubexpIndex("key")]
+				key := m[1]
 				value := m[4]
 				if value == "" {
 					value = m[5]
@@ -103,22 +251,76 @@ func ExtractDefinitionsOnSpec(s Spec, filter *func(bool) bool) (DefinitionList,
 					value = TrueString
 				}
 
+				if _, exists := def.Arguments[key]; exists {
+					report(Diagnostic{
+						Severity:     SeverityError,
+						Code:         CodeAttributeDuplicateKey,
+						Message:      fmt.Sprintf(WarnAttributeDuplicateKey, key, c),
+						Position:     cm.position,
+						SuggestedFix: fmt.Sprintf("remove the duplicate attribute '%v'", key),
+					})
+					return
+				}
+
 				def.Arguments[key] = value
 			}
 
 			if len(attributeString) > 0 {
-				warnMu.Lock()
-				warnings = append(warnings, fmt.Sprintf(WarnAttributeWrongFormat, m[2]))
-				warnMu.Unlock()
+				report(Diagnostic{
+					Severity:     SeverityError,
+					Code:         CodeAttributeWrongFormat,
+					Message:      fmt.Sprintf(WarnAttributeWrongFormat, m[2]),
+					Position:     cm.position,
+					SuggestedFix: "quote values containing a separator",
+				})
 				return
 			}
 
-			dlMu.Lock()
-			defer dlMu.Unlock()
-			dl = append(dl, def)
-		}(c)
+			defs[idx] = &def
+		}(idx, cm)
 	}
 	wg.Wait()
 
-	return dl, warnings
+	var diagnostics Diagnostics
+	for _, d := range reported {
+		if d != nil {
+			diagnostics = append(diagnostics, *d)
+		}
+	}
+
+	seen := map[string]int{}
+	second := map[string]Position{}
+	var identifiers []string
+	for idx, def := range defs {
+		if def == nil {
+			continue
+		}
+
+		if seen[def.Identifier] == 0 {
+			identifiers = append(identifiers, def.Identifier)
+		}
+		seen[def.Identifier]++
+		if a.Cardinalities[def.Identifier] == Unique && seen[def.Identifier] > 1 {
+			if seen[def.Identifier] == 2 {
+				second[def.Identifier] = cs[idx].position
+			}
+			continue // only the first definition of unique annotations is kept
+		}
+
+		dl = append(dl, *def)
+	}
+
+	for _, identifier := range identifiers {
+		if n := seen[identifier]; a.Cardinalities[identifier] == Unique && n > 1 {
+			diagnostics = append(diagnostics, Diagnostic{
+				Severity:     SeverityError,
+				Code:         CodeDefinitionNotRepeatable,
+				Message:      fmt.Sprintf(WarnDefinitionNotRepeatable, identifier, n),
+				Position:     second[identifier],
+				SuggestedFix: fmt.Sprintf("remove the additional definitions of '%v'", identifier),
+			})
+		}
+	}
+
+	return dl, diagnostics
 }
@@ -82,7 +82,7 @@ func Test_ExtractDefinitionsForFunction(t *testing.T) {
 	t.Run("error", func(t *testing.T) {
 		for _, tc := range []struct {
 			c string
-			w Warnings
+			w []string
 		}{
 			{
 				`chariot.route{some_key_without_value=;something_else={"json":"example"}`, // missing end
@@ -119,8 +119,8 @@ func Test_ExtractDefinitionsForFunction(t *testing.T) {
 				}
 
 				for idx, w := range warnings {
-					if w != tc.w[idx] {
-						t.Errorf("warning didn't match, got\n'%v'\n, want \n'%v'", w, tc.w[idx])
+					if w.Message != tc.w[idx] {
+						t.Errorf("warning didn't match, got\n'%v'\n, want \n'%v'", w.Message, tc.w[idx])
 					}
 				}
 			})
@@ -153,3 +153,173 @@ func Test_ExtractDefinitionsForFunction(t *testing.T) {
 		}
 	})
 }
+
+func Test_AnalyzerExtractDefinitions(t *testing.T) {
+	t.Run("duplicate key", func(t *testing.T) {
+		c := fmt.Sprintf(`crud.field{name=id%sname=identifier}`, Separator)
+		defs, warnings := Analyzer{}.ExtractDefinitions(inspect.Function{
+			Comments: []string{c},
+		})
+
+		if len(defs) > 0 {
+			t.Error("didn't expect definitions")
+		}
+
+		if len(warnings) != 1 || warnings[0].Message != fmt.Sprintf(WarnAttributeDuplicateKey, "name", c) {
+			t.Errorf("unexpected warnings %v", warnings)
+		}
+	})
+
+	t.Run("order preserved", func(t *testing.T) {
+		defs, warnings := Analyzer{}.ExtractDefinitions(inspect.Function{
+			Comments: []string{
+				`a{}`,
+				`b{}`,
+				`c{}`,
+			},
+		})
+
+		if len(warnings) > 0 {
+			t.Error("expected no warnings")
+		}
+
+		if len(defs) != 3 || defs[0].Identifier != "a" || defs[1].Identifier != "b" || defs[2].Identifier != "c" {
+			t.Errorf("expected definitions in order, got %v", defs)
+		}
+	})
+
+	t.Run("repeatable", func(t *testing.T) {
+		defs, warnings := Analyzer{}.ExtractDefinitions(inspect.Type{
+			Comments: []string{
+				`crud.model{name=users}`,
+				`crud.model{name=accounts}`,
+			},
+		})
+
+		if len(warnings) > 0 {
+			t.Error("expected no warnings")
+		}
+
+		if len(defs) != 2 {
+			t.Errorf("expected 2 definitions, got %v", len(defs))
+		}
+	})
+
+	t.Run("unique", func(t *testing.T) {
+		defs, warnings := Analyzer{
+			Cardinalities: Cardinalities{
+				"crud.model": Unique,
+			},
+		}.ExtractDefinitions(inspect.Type{
+			Comments: []string{
+				`crud.model{name=users}`,
+				`crud.field{name=id}`,
+				`crud.model{name=accounts}`,
+			},
+		})
+
+		if len(warnings) != 1 || warnings[0].Message != fmt.Sprintf(WarnDefinitionNotRepeatable, "crud.model", 2) {
+			t.Errorf("unexpected warnings %v", warnings)
+		}
+
+		if len(defs) != 2 || defs[0].Arguments["name"] != "users" || defs[1].Identifier != "crud.field" {
+			t.Errorf("expected first unique definition to be kept, got %v", defs)
+		}
+	})
+}
+
+func Test_AnalyzerMarker(t *testing.T) {
+	t.Run("marker required", func(t *testing.T) {
+		defs, warnings := Analyzer{
+			Marker: "@",
+		}.ExtractDefinitions(inspect.Function{
+			Comments: []string{
+				`map{key} is used for lookups`,
+				`a map{ which is not closed`,
+				`@chariot.route{method=GET}`,
+			},
+		})
+
+		if len(warnings) > 0 {
+			t.Errorf("expected no warnings, got %v", warnings)
+		}
+
+		if len(defs) != 1 || defs[0].Identifier != "chariot.route" || defs[0].Arguments["method"] != "GET" {
+			t.Errorf("expected only marked definition, got %v", defs)
+		}
+	})
+
+	t.Run("marked comments are not filtered", func(t *testing.T) {
+		_, warnings := Analyzer{
+			Filter: FilterCommentNoAnnotation(),
+			Marker: "+ann:",
+		}.ExtractDefinitions(inspect.Function{
+			Comments: []string{
+				`+ann:chariot route{}`,
+			},
+		})
+
+		if len(warnings) != 1 || warnings[0].Message != fmt.Sprintf(WarnFormatWrongFormat, `chariot route{}`) {
+			t.Errorf("unexpected warnings %v", warnings)
+		}
+	})
+
+	t.Run("namespaces", func(t *testing.T) {
+		defs, warnings := Analyzer{
+			Namespaces: []string{"crud", "chariot.route"},
+		}.ExtractDefinitions(inspect.Function{
+			Comments: []string{
+				`crud.model{}`,
+				`crud{}`,
+				`crudness.model{}`,
+				`chariot.route{}`,
+				`chariot.handler{}`,
+				`map{}`,
+			},
+		})
+
+		if len(warnings) > 0 {
+			t.Errorf("expected no warnings, got %v", warnings)
+		}
+
+		if len(defs) != 3 || defs[0].Identifier != "crud.model" || defs[1].Identifier != "crud" || defs[2].Identifier != "chariot.route" {
+			t.Errorf("unexpected definitions %v", defs)
+		}
+	})
+}
+
+func Test_AnalyzerLineComments(t *testing.T) {
+	defs, warnings := Analyzer{
+		Filter: FilterCommentNoAnnotation(),
+	}.ExtractDefinitions(inspect.Field{
+		Comments: []string{
+			`crud.field{name=name}`,
+		},
+		LineComments: []string{
+			`crud.index{unique}`,
+		},
+	})
+
+	if len(warnings) > 0 {
+		t.Errorf("expected no warnings, got %v", warnings)
+	}
+
+	if len(defs) != 2 ||
+		defs[0].Identifier != "crud.field" || defs[0].Source != SourceDoc ||
+		defs[1].Identifier != "crud.index" || defs[1].Source != SourceLine {
+		t.Errorf("unexpected definitions %v", defs)
+	}
+}
+
+func Test_AnalyzerWhitespace(t *testing.T) {
+	// whitespace around attributes doesn't match the attribute format of grammar version 1
+	for _, c := range []string{
+		fmt.Sprintf(`x{a=1%s b=2}`, Separator),
+		`crud.field{name id}`,
+	} {
+		defs, warnings := Analyzer{}.ExtractDefinitions(inspect.Function{Comments: []string{c}})
+		if len(defs) != 0 || len(warnings) != 1 || warnings[0].Code != CodeAttributeWrongFormat {
+			t.Errorf("expected '%v' to be rejected, got %v %v", c, defs, warnings)
+		}
+	}
+}
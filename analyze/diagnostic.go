@@ -0,0 +1,127 @@
+package analyze
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Severity classifies how critical a diagnostic is
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return fmt.Sprintf("severity(%d)", int(s))
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(b []byte) error {
+	for _, sev := range []Severity{SeverityInfo, SeverityWarning, SeverityError} {
+		if sev.String() == string(b) {
+			*s = sev
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown severity '%s'", b)
+}
+
+const (
+	CodeFormatBrackets          = "format-brackets"
+	CodeFormatWrongFormat       = "format-definition"
+	CodeAttributeWrongFormat    = "attribute-format"
+	CodeAttributeDuplicateKey   = "attribute-duplicate"
+	CodeDefinitionNotRepeatable = "definition-not-repeatable"
+	CodeUnknownIdentifier       = "unknown-identifier"
+	CodeTargetNotAllowed        = "target-not-allowed"
+	CodeAttributeUnknown        = "attribute-unknown"
+	CodeAttributeMissing        = "attribute-missing"
+	CodeAttributeType           = "attribute-type"
+)
+
+// Position locates a diagnostic in source; unknown parts are left empty
+type Position struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+func (p Position) String() string {
+	s := p.File
+	if s == "" {
+		s = "-"
+	}
+	if p.Line > 0 {
+		s = fmt.Sprintf("%s:%d", s, p.Line)
+		if p.Column > 0 {
+			s = fmt.Sprintf("%s:%d", s, p.Column)
+		}
+	}
+	return s
+}
+
+// Diagnostic is a single finding while analyzing annotations
+type Diagnostic struct {
+	Severity     Severity `json:"severity"`
+	Code         string   `json:"code"`
+	Message      string   `json:"message"`
+	Position     Position `json:"position"`
+	Spec         string   `json:"spec,omitempty"`
+	SuggestedFix string   `json:"suggested_fix,omitempty"`
+}
+
+func (d Diagnostic) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s: %s (%s)", d.Position, d.Severity, d.Message, d.Code)
+	if d.Spec != "" {
+		fmt.Fprintf(&b, " on %s", d.Spec)
+	}
+	return b.String()
+}
+
+type Diagnostics []Diagnostic
+
+// Err returns all diagnostics joined into a single error, or nil if there are none
+func (ds Diagnostics) Err() error {
+	var errs []error
+	for _, d := range ds {
+		errs = append(errs, d)
+	}
+	return errors.Join(errs...)
+}
+
+// AtLeast returns all diagnostics with a severity of at least min
+func (ds Diagnostics) AtLeast(min Severity) Diagnostics {
+	var found Diagnostics
+	for _, d := range ds {
+		if d.Severity >= min {
+			found = append(found, d)
+		}
+	}
+	return found
+}
+
+// WithSpec returns a copy of the diagnostics, referencing the spec passed
+func (ds Diagnostics) WithSpec(spec string) Diagnostics {
+	var found Diagnostics
+	for _, d := range ds {
+		d.Spec = spec
+		found = append(found, d)
+	}
+	return found
+}
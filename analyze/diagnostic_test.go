@@ -0,0 +1,91 @@
+package analyze
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/troublete/go-annotation/inspect"
+)
+
+func Test_Diagnostic(t *testing.T) {
+	t.Run("error", func(t *testing.T) {
+		for _, tc := range []struct {
+			d Diagnostic
+			e string
+		}{
+			{
+				Diagnostic{Severity: SeverityWarning, Code: CodeFormatBrackets, Message: WarnFormatBrackets},
+				"-: warning: bracket structure doesn't match (format-brackets)",
+			},
+			{
+				Diagnostic{
+					Severity: SeverityError,
+					Code:     CodeAttributeWrongFormat,
+					Message:  "message",
+					Position: Position{File: "a.go", Line: 3},
+					Spec:     "demo.A",
+				},
+				"a.go:3: error: message (attribute-format) on demo.A",
+			},
+			{
+				Diagnostic{Severity: SeverityInfo, Code: "code", Message: "message", Position: Position{File: "a.go", Line: 3, Column: 4}},
+				"a.go:3:4: info: message (code)",
+			},
+		} {
+			if tc.d.Error() != tc.e {
+				t.Errorf("error didn't match (has=%v, want=%v)", tc.d.Error(), tc.e)
+			}
+		}
+	})
+
+	t.Run("err", func(t *testing.T) {
+		if (Diagnostics{}).Err() != nil {
+			t.Error("expected no error")
+		}
+
+		d := Diagnostic{Severity: SeverityError, Code: "code", Message: "message"}
+		err := Diagnostics{d, {Severity: SeverityWarning}}.Err()
+
+		var found Diagnostic
+		if !errors.As(err, &found) || found != d {
+			t.Error("expected diagnostic to be joined into error")
+		}
+	})
+
+	t.Run("at least", func(t *testing.T) {
+		ds := Diagnostics{{Severity: SeverityInfo}, {Severity: SeverityWarning}, {Severity: SeverityError}}
+		if len(ds.AtLeast(SeverityWarning)) != 2 || len(ds.AtLeast(SeverityError)) != 1 {
+			t.Error("unexpected filter result")
+		}
+	})
+
+	t.Run("severity text", func(t *testing.T) {
+		var s Severity
+		if err := s.UnmarshalText([]byte("warning")); err != nil || s != SeverityWarning {
+			t.Error("expected warning severity")
+		}
+
+		if err := s.UnmarshalText([]byte("fatal")); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func Test_DiagnosticPosition(t *testing.T) {
+	_, diagnostics := Analyzer{}.ExtractDefinitions(inspect.Field{
+		FilePath:         "model.go",
+		Comments:         []string{`crud.field{name=id}`, `crud.field{name=`},
+		CommentLines:     []int{4, 5},
+		LineComments:     []string{`crud.index{a,a}`},
+		LineCommentLines: []int{6},
+	})
+
+	if len(diagnostics) != 2 ||
+		diagnostics[0].Code != CodeFormatBrackets ||
+		diagnostics[0].Position != (Position{File: "model.go", Line: 5}) ||
+		diagnostics[1].Code != CodeAttributeDuplicateKey ||
+		diagnostics[1].Severity != SeverityError ||
+		diagnostics[1].Position != (Position{File: "model.go", Line: 6}) {
+		t.Errorf("unexpected diagnostics %v", diagnostics)
+	}
+}
@@ -0,0 +1,73 @@
+package analyze
+
+import "strings"
+
+// Directive is a structured representation of a directive comment like `//go:generate stringer -type=Kind`,
+// `//lint:ignore U1000 reason` or `//nolint`
+type Directive struct {
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments,omitempty"`
+}
+
+type DirectiveList []Directive
+
+// DirectiveSpec is implemented by specs which carry directive comments; directive lines are expected without the
+// leading slashes (e.g. `go:generate stringer -type=Kind`)
+type DirectiveSpec interface {
+	DirectiveDoc() []string
+}
+
+// ParseDirective parses a single directive line into its namespace, name and arguments; `x:y args` is split into
+// namespace x, name y and the arguments, directives without namespace (e.g. `nolint`, `line file.go:1`) only carry a
+// name
+func ParseDirective(line string) Directive {
+	head, args, _ := strings.Cut(strings.TrimSpace(line), " ")
+
+	d := Directive{
+		Name:      head,
+		Arguments: strings.TrimSpace(args),
+	}
+	if ns, name, ok := strings.Cut(head, ":"); ok {
+		d.Namespace = ns
+		d.Name = name
+	}
+	return d
+}
+
+// FileDirectiveSpec is implemented by specs which carry the directive comments of their file, i.e. the comments above
+// the package clause like build constraints (e.g. `go:build linux` or `+build linux`)
+type FileDirectiveSpec interface {
+	FileDirectiveDoc() []string
+}
+
+// ExtractDirectivesOnSpec extracts all directives of a spec, in the order they are declared
+func ExtractDirectivesOnSpec(s DirectiveSpec) DirectiveList {
+	return parseDirectives(s.DirectiveDoc())
+}
+
+// ExtractFileDirectivesOnSpec extracts all directives of the file of a spec, in the order they are declared; legacy
+// build constraints are named `+build`
+func ExtractFileDirectivesOnSpec(s FileDirectiveSpec) DirectiveList {
+	return parseDirectives(s.FileDirectiveDoc())
+}
+
+func parseDirectives(lines []string) DirectiveList {
+	var dl DirectiveList
+	for _, l := range lines {
+		dl = append(dl, ParseDirective(l))
+	}
+	return dl
+}
+
+// Find returns all directives matching namespace and name; an empty name matches all directives of the namespace
+// Used to e.g. cross check `go:generate` directives against annotations
+func (dl DirectiveList) Find(namespace, name string) DirectiveList {
+	var found DirectiveList
+	for _, d := range dl {
+		if d.Namespace == namespace && (name == "" || d.Name == name) {
+			found = append(found, d)
+		}
+	}
+	return found
+}
@@ -0,0 +1,63 @@
+package analyze
+
+import (
+	"testing"
+
+	"github.com/troublete/go-annotation/inspect"
+)
+
+func Test_ParseDirective(t *testing.T) {
+	for _, tc := range []struct {
+		l string
+		d Directive
+	}{
+		{
+			"go:generate stringer -type=Kind",
+			Directive{Namespace: "go", Name: "generate", Arguments: "stringer -type=Kind"},
+		},
+		{
+			"go:embed",
+			Directive{Namespace: "go", Name: "embed"},
+		},
+		{
+			"lint:ignore U1000 not used yet",
+			Directive{Namespace: "lint", Name: "ignore", Arguments: "U1000 not used yet"},
+		},
+		{
+			"nolint",
+			Directive{Name: "nolint"},
+		},
+		{
+			"nolint:errcheck,gosec",
+			Directive{Namespace: "nolint", Name: "errcheck,gosec"},
+		},
+		{
+			"line file.go:10",
+			Directive{Name: "line", Arguments: "file.go:10"},
+		},
+	} {
+		t.Run(tc.l, func(t *testing.T) {
+			if d := ParseDirective(tc.l); d != tc.d {
+				t.Errorf("directive didn't match (has=%v, want=%v)", d, tc.d)
+			}
+		})
+	}
+}
+
+func Test_ExtractDirectivesOnSpec(t *testing.T) {
+	dl := ExtractDirectivesOnSpec(inspect.Function{
+		Directives: []string{
+			"go:generate stringer -type=Kind",
+			"go:generate go run ./cmd/inspect",
+			"nolint",
+		},
+	})
+
+	if len(dl) != 3 {
+		t.Errorf("expected 3 directives, got %v", len(dl))
+	}
+
+	if len(dl.Find("go", "generate")) != 2 || len(dl.Find("go", "")) != 2 || len(dl.Find("go", "embed")) != 0 {
+		t.Error("find failed expectation")
+	}
+}
@@ -0,0 +1,102 @@
+package analyze
+
+import "strings"
+
+// lines is a spec consisting of documentation lines only
+type lines []string
+
+func (l lines) Doc() []string {
+	return l
+}
+
+// Fix returns the comment line with the problem diagnosed by code fixed; fixes are known for unbalanced curly brackets
+// (CodeFormatBrackets) and unquoted values containing a separator (CodeAttributeWrongFormat). A fix is only returned,
+// if the fixed comment is a valid annotation
+func (a Analyzer) Fix(c, code string) (string, bool) {
+	marker := ""
+	if a.Marker != "" && strings.HasPrefix(c, a.Marker) {
+		marker, c = a.Marker, strings.TrimPrefix(c, a.Marker)
+	}
+
+	var fixed string
+	switch code {
+	case CodeFormatBrackets:
+		fixed = balanceBrackets(c)
+	case CodeAttributeWrongFormat:
+		var ok bool
+		fixed, ok = quoteSeparators(a.grammar(), c)
+		if !ok {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+	fixed = marker + fixed
+
+	a.Filter = nil
+	dl, diagnostics := a.ExtractDefinitions(lines{fixed})
+	if len(dl) != 1 || len(diagnostics) > 0 {
+		return "", false
+	}
+	return fixed, true
+}
+
+// balanceBrackets appends missing closing brackets or removes trailing superfluous ones
+func balanceBrackets(c string) string {
+	diff := strings.Count(c, "{") - strings.Count(c, "}")
+	for ; diff < 0 && strings.HasSuffix(c, "}"); diff++ {
+		c = strings.TrimSuffix(c, "}")
+	}
+	if diff > 0 {
+		c += strings.Repeat("}", diff)
+	}
+	return c
+}
+
+// quoteSeparators joins parts of an attribute list, which are no attributes, with the attribute before (as they were
+// separated by an unquoted separator in a value) and quotes all values containing a separator
+func quoteSeparators(g Grammar, c string) (string, bool) {
+	m := g.Definition.FindStringSubmatch(c)
+	if len(m) < 3 {
+		return "", false
+	}
+
+	var attributes []string
+	for _, part := range splitUnquoted(m[2]) {
+		part = strings.TrimSpace(part)
+		if g.Argument.FindString(part) == part || len(attributes) == 0 {
+			attributes = append(attributes, part)
+			continue
+		}
+		attributes[len(attributes)-1] += Separator + part
+	}
+
+	for idx, attribute := range attributes {
+		key, value, ok := strings.Cut(attribute, "=")
+		if !ok || strings.HasPrefix(value, `"`) || !strings.Contains(value, Separator) {
+			continue
+		}
+		if strings.Contains(value, `"`) {
+			return "", false
+		}
+		attributes[idx] = key + `="` + value + `"`
+	}
+
+	return m[1] + "{" + strings.Join(attributes, Separator) + "}", true
+}
+
+// splitUnquoted splits an attribute list on separators outside of quotes
+func splitUnquoted(s string) []string {
+	var parts []string
+	start, quoted := 0, false
+	for idx := 0; idx < len(s); idx++ {
+		switch {
+		case s[idx] == '"':
+			quoted = !quoted
+		case !quoted && strings.HasPrefix(s[idx:], Separator):
+			parts = append(parts, s[start:idx])
+			start = idx + len(Separator)
+		}
+	}
+	return append(parts, s[start:])
+}
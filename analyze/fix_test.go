@@ -0,0 +1,48 @@
+package analyze
+
+import "testing"
+
+func Test_AnalyzerFix(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		analyzer Analyzer
+		comment  string
+		code     string
+		fixed    string
+	}{
+		{name: "missing bracket", comment: `crud.model{name=users`, code: CodeFormatBrackets, fixed: `crud.model{name=users}`},
+		{name: "superfluous bracket", comment: `crud.model{name=users}}`, code: CodeFormatBrackets, fixed: `crud.model{name=users}`},
+		{name: "bracket with marker", analyzer: Analyzer{Marker: "@"}, comment: `@crud.model{`, code: CodeFormatBrackets, fixed: `@crud.model{}`},
+		{name: "unfixable bracket", comment: `crud.model name=users}`, code: CodeFormatBrackets},
+		{name: "unquoted separator", comment: `chariot.route{path=/a,/b,method=GET}`, code: CodeAttributeWrongFormat, fixed: `chariot.route{path="/a,/b",method=GET}`},
+		{name: "unquoted separator with spaces", comment: `chariot.route{method=GET, path=/a, /b}`, code: CodeAttributeWrongFormat, fixed: `chariot.route{method=GET,path="/a,/b"}`},
+		{name: "quoted values kept", comment: `chariot.route{path="/a,/b",tags=x,/y}`, code: CodeAttributeWrongFormat, fixed: `chariot.route{path="/a,/b",tags="x,/y"}`},
+		{name: "unfixable quote", comment: `chariot.route{path=/a",/b}`, code: CodeAttributeWrongFormat},
+		{name: "unknown code", comment: `crud.model{name=users,name=x}`, code: CodeAttributeDuplicateKey},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fixed, ok := tc.analyzer.Fix(tc.comment, tc.code)
+			if ok != (tc.fixed != "") || fixed != tc.fixed {
+				t.Errorf("unexpected fix (has=%v, want=%v, ok=%v)", fixed, tc.fixed, ok)
+			}
+		})
+	}
+}
+
+func Test_AnalyzerFixDiagnosed(t *testing.T) {
+	a := Analyzer{}
+	for _, c := range []string{`crud.model{name=users`, `chariot.route{path=/a,/b}`} {
+		_, diagnostics := a.ExtractDefinitions(lines{c})
+		if len(diagnostics) != 1 {
+			t.Fatalf("expected diagnostic on %v", c)
+		}
+
+		fixed, ok := a.Fix(c, diagnostics[0].Code)
+		if !ok {
+			t.Fatalf("expected fix of %v", diagnostics[0].Code)
+		}
+		if _, diagnostics := a.ExtractDefinitions(lines{fixed}); len(diagnostics) > 0 {
+			t.Errorf("expected fixed comment to be valid, got %v", diagnostics)
+		}
+	}
+}
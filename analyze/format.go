@@ -0,0 +1,215 @@
+package analyze
+
+import (
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"maps"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/troublete/go-annotation/inspect"
+)
+
+// FormatOptions configure the canonical form annotations are formatted into
+type FormatOptions struct {
+	// Analyzer declares which comments are annotations; if no filter is set, comments not matching the definition
+	// form are skipped
+	Analyzer Analyzer
+
+	// SortKeys sorts the attributes by key, otherwise their order is preserved
+	SortKeys bool
+
+	// TrailingSeparator writes a separator after the last attribute, otherwise trailing separators are removed
+	TrailingSeparator bool
+}
+
+// Format returns the comment line in canonical form, if it is an annotation: the form of FormatDefinition, with no
+// whitespace around identifier, keys, values and separators outside of quotes, attributes separated by the bare
+// separator, values quoted only if needed and attributes with the value TRUE written key-only (e.g.
+// `crud.field{name="id, key",primary}`). Comments which are no annotations are returned as is, annotations with
+// diagnostics fail
+func Format(c string, opts FormatOptions) (string, error) {
+	a := opts.Analyzer
+	if a.Filter == nil {
+		a.Filter = FilterCommentNoAnnotation()
+	}
+
+	normalized := c
+	if strings.HasPrefix(c, a.Marker) {
+		normalized = a.Marker + normalizeDefinition(strings.TrimPrefix(c, a.Marker))
+	}
+
+	dl, diagnostics := a.ExtractDefinitions(lines{normalized})
+	if len(diagnostics) > 0 {
+		return "", fmt.Errorf("%s (%s)", diagnostics[0].Message, diagnostics[0].Code)
+	}
+	if len(dl) == 0 {
+		return c, nil
+	}
+	def := dl[0]
+
+	// keys are read again as the definition doesn't preserve their order
+	m := a.grammar().Definition.FindStringSubmatch(strings.TrimPrefix(normalized, a.Marker))
+	var keys []string
+	for _, am := range a.grammar().Argument.FindAllStringSubmatch(m[2], -1) {
+		keys = append(keys, am[1])
+	}
+	if opts.SortKeys {
+		sort.Strings(keys)
+	}
+
+	attrs, err := formatAttributes(keys, def.Arguments)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString(a.Marker)
+	b.WriteString(def.Identifier)
+	b.WriteString("{")
+	b.WriteString(strings.Join(attrs, Separator))
+	if opts.TrailingSeparator && len(attrs) > 0 {
+		b.WriteString(Separator)
+	}
+	b.WriteString("}")
+	formatted := b.String()
+
+	check, diagnostics := a.ExtractDefinitions(lines{formatted})
+	if len(check) != 1 || len(diagnostics) > 0 ||
+		check[0].Identifier != def.Identifier || !maps.Equal(check[0].Arguments, def.Arguments) {
+		return "", fmt.Errorf("annotation '%v' can't be formatted without changing its attributes", c)
+	}
+	return formatted, nil
+}
+
+// FormatSource formats all annotations in the comments of the Go source in place; the source is returned unchanged
+// apart from the annotations. If the source can't be parsed or any annotation fails to format, all errors are
+// returned with their position, joined
+func FormatSource(filename string, src []byte, opts FormatOptions) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	type replacement struct {
+		start, end int
+		text       string
+	}
+
+	var replacements []replacement
+	var errs []error
+	for _, cg := range f.Comments {
+		for _, c := range cg.List {
+			var text string
+			var err error
+			switch c.Text[1] {
+			case '/':
+				if inspect.IsDirective(c.Text) {
+					continue
+				}
+				text, err = formatCommentLine(c.Text[2:], opts)
+				text = "//" + text
+			case '*':
+				var ls []string
+				for _, l := range strings.Split(c.Text[2:len(c.Text)-2], "\n") {
+					l, lerr := formatCommentLine(l, opts)
+					err = errors.Join(err, lerr)
+					ls = append(ls, l)
+				}
+				text = "/*" + strings.Join(ls, "\n") + "*/"
+			}
+
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%v: %w", fset.Position(c.Pos()), err))
+				continue
+			}
+			if text != c.Text {
+				start := fset.Position(c.Pos()).Offset
+				replacements = append(replacements, replacement{start: start, end: start + len(c.Text), text: text})
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, r := range replacements {
+		b.Write(src[last:r.start])
+		b.WriteString(r.text)
+		last = r.end
+	}
+	b.Write(src[last:])
+	return []byte(b.String()), nil
+}
+
+// normalizeDefinition removes the whitespace around identifier, keys, values and separators of a definition, as well
+// as a trailing separator, so it can be read by grammars not allowing any; whitespace within quotes is kept. Text
+// which isn't of the form `identifier{...}` is returned as is
+func normalizeDefinition(text string) string {
+	brace := strings.Index(text, "{")
+	if brace < 0 || !strings.HasSuffix(text, "}") {
+		return text
+	}
+	identifier := strings.TrimSpace(text[:brace])
+	if identifier == "" || strings.ContainsFunc(identifier, unicode.IsSpace) {
+		return text
+	}
+
+	parts, quoted := SplitAttributes(text[brace+1 : len(text)-1])
+	if quoted {
+		return text
+	}
+	if len(parts) > 0 && strings.TrimSpace(parts[len(parts)-1]) == "" {
+		parts = parts[:len(parts)-1]
+	}
+
+	attrs := make([]string, 0, len(parts))
+	for _, p := range parts {
+		key, value, ok := strings.Cut(p, "=")
+		attr := strings.TrimSpace(key)
+		if ok {
+			attr += "=" + strings.TrimSpace(value)
+		}
+		attrs = append(attrs, attr)
+	}
+	return identifier + "{" + strings.Join(attrs, Separator) + "}"
+}
+
+// SplitAttributes splits an attribute list on separators outside of quotes; it reports if the list ends within quotes
+func SplitAttributes(s string) ([]string, bool) {
+	var parts []string
+	start, quoted := 0, false
+	for idx := 0; idx < len(s); idx++ {
+		switch {
+		case s[idx] == '"':
+			quoted = !quoted
+		case !quoted && strings.HasPrefix(s[idx:], Separator):
+			parts = append(parts, s[start:idx])
+			start = idx + len(Separator)
+		}
+	}
+	return append(parts, s[start:]), quoted
+}
+
+// formatCommentLine formats the annotation of a comment line, keeping the surrounding whitespace
+func formatCommentLine(l string, opts FormatOptions) (string, error) {
+	text := strings.TrimLeftFunc(l, unicode.IsSpace)
+	lead := l[:len(l)-len(text)]
+	text = strings.TrimRightFunc(text, unicode.IsSpace)
+	trail := l[len(lead)+len(text):]
+	if text == "" {
+		return l, nil
+	}
+
+	formatted, err := Format(text, opts)
+	if err != nil {
+		return "", err
+	}
+	return lead + formatted + trail, nil
+}
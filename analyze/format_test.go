@@ -0,0 +1,122 @@
+package analyze
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+)
+
+// baselineGrammar is the original grammar version 1, which doesn't allow whitespace around attributes
+var baselineGrammar = Grammar{
+	Version:    1,
+	Definition: regexp.MustCompile(`^([a-zA-Z\.\_]+)\s{0,1}\{(.*)\}$`),
+	Argument: regexp.MustCompile(
+		fmt.Sprintf(`(?P<key>[a-zA-Z_]+)(=("(?P<value>[^"]*)"|(?P<value>[^%s]*)))?%s?`, Separator, Separator),
+	),
+}
+
+func Test_Format(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		opts      FormatOptions
+		comment   string
+		formatted string
+		err       bool
+	}{
+		{name: "canonical", comment: `crud.field{name=id,primary}`, formatted: `crud.field{name=id,primary}`},
+		{name: "spacing", comment: `crud.field {name=id,primary,nullable}`, formatted: `crud.field{name=id,primary,nullable}`},
+		{name: "needless quotes", comment: `crud.field{name="id",doc="id, key"}`, formatted: `crud.field{name=id,doc="id, key"}`},
+		{name: "surrounding whitespace kept", comment: `crud.field{name=" id"}`, formatted: `crud.field{name=" id"}`},
+		{name: "empty value", comment: `crud.field{name=""}`, formatted: `crud.field{name=}`},
+		{name: "true key-only", comment: `crud.field{primary=TRUE}`, formatted: `crud.field{primary}`},
+		{name: "trailing separator removed", comment: `crud.field{name=id,}`, formatted: `crud.field{name=id}`},
+		{name: "spaced attributes", comment: `x{a, b,}`, formatted: `x{a,b}`},
+		{name: "spaced identifier and attributes", comment: `x {a, b,}`, formatted: `x{a,b}`},
+		{name: "spaced values", comment: `x{b="1", a=TRUE}`, formatted: `x{b=1,a}`},
+		{name: "spaced equals", comment: `crud.field{ name = "id, key" , primary }`, formatted: `crud.field{name="id, key",primary}`},
+		{name: "trailing separator", opts: FormatOptions{TrailingSeparator: true}, comment: `crud.field{name=id,primary}`, formatted: `crud.field{name=id,primary,}`},
+		{name: "no attributes", opts: FormatOptions{TrailingSeparator: true}, comment: `crud.model {}`, formatted: `crud.model{}`},
+		{name: "order preserved", comment: `crud.field{name=id,max_length=10}`, formatted: `crud.field{name=id,max_length=10}`},
+		{name: "sorted", opts: FormatOptions{SortKeys: true}, comment: `crud.field{name=id,max_length=10}`, formatted: `crud.field{max_length=10,name=id}`},
+		{name: "marker", opts: FormatOptions{Analyzer: Analyzer{Marker: "@"}}, comment: `@crud.field{name="id"}`, formatted: `@crud.field{name=id}`},
+		{name: "no marker", opts: FormatOptions{Analyzer: Analyzer{Marker: "@"}}, comment: `crud.field{name="id"}`, formatted: `crud.field{name="id"}`},
+		{name: "prose", comment: `User is a user`, formatted: `User is a user`},
+		{name: "grammar v2", opts: FormatOptions{Analyzer: Analyzer{Grammar: GrammarV2}}, comment: `my-org.limit{max_len2="5"}`, formatted: `my-org.limit{max_len2=5}`},
+		{name: "duplicate key", comment: `crud.field{name=id,name=key}`, err: true},
+		{name: "unbalanced brackets", comment: `crud.field{name=id`, err: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			formatted, err := Format(tc.comment, tc.opts)
+			if (err != nil) != tc.err || formatted != tc.formatted {
+				t.Errorf("unexpected format (has=%v, want=%v, err=%v)", formatted, tc.formatted, err)
+			}
+			if err != nil || formatted == tc.comment {
+				return
+			}
+
+			// the canonical form is valid in the original grammar
+			a := tc.opts.Analyzer
+			if a.Grammar.Version < 2 {
+				a.Grammar = baselineGrammar
+			}
+			if dl, diagnostics := a.ExtractDefinitions(lines{formatted}); len(dl) != 1 || len(diagnostics) > 0 {
+				t.Errorf("expected '%v' to parse (diagnostics=%v)", formatted, diagnostics)
+			}
+		})
+	}
+}
+
+func Test_FormatSource(t *testing.T) {
+	src := `package demo
+
+//go:generate echo {
+//nolint:unused
+// User is a user
+//
+// crud.model {name="users"}
+type User struct {
+	ID   string ` + "`json:\"id\"`" + ` //  crud.field {name="id",primary}
+	Name string /* crud.field{name=name} */
+}
+
+/*
+	crud.model{name="accounts",}
+*/
+type Account struct{}
+`
+
+	want := `package demo
+
+//go:generate echo {
+//nolint:unused
+// User is a user
+//
+// crud.model{name=users}
+type User struct {
+	ID   string ` + "`json:\"id\"`" + ` //  crud.field{name=id,primary}
+	Name string /* crud.field{name=name} */
+}
+
+/*
+	crud.model{name=accounts}
+*/
+type Account struct{}
+`
+
+	formatted, err := FormatSource("demo.go", []byte(src), FormatOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(formatted) != want {
+		t.Errorf("unexpected source:\n%s", formatted)
+	}
+
+	_, err = FormatSource("demo.go", []byte("package demo\n\n// crud.model{name=a,name=b}\ntype A struct{}\n"), FormatOptions{})
+	if err == nil || err.Error() != "demo.go:3:1: attribute 'name' is defined more than once in 'crud.model{name=a,name=b}' (attribute-duplicate)" {
+		t.Errorf("expected positioned error, got %v", err)
+	}
+
+	if _, err := FormatSource("demo.go", []byte("package"), FormatOptions{}); err == nil {
+		t.Error("expected syntax error")
+	}
+}
@@ -0,0 +1,54 @@
+package analyze
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Grammar defines the expressions an annotation is parsed with; the argument expression MUST provide the key as first
+// and the (quoted and unquoted) value as fourth and fifth submatch
+type Grammar struct {
+	Version    int
+	Definition *regexp.Regexp
+	Argument   *regexp.Regexp
+}
+
+var (
+	// GrammarV1 is the original grammar, identifiers consist of [a-zA-Z._] and keys of [a-zA-Z_]; it is used if no
+	// grammar is set on the analyzer
+	GrammarV1 = Grammar{
+		Version:    1,
+		Definition: DefinitionRe,
+		Argument:   ArgumentRe,
+	}
+
+	// GrammarV2 allows Unicode letters and (non leading) digits in identifiers and keys, and hyphens in identifier
+	// namespaces (e.g. `v2.route{}`, `my-org.limit_10s{max_len2=5}`)
+	GrammarV2 = Grammar{
+		Version: 2,
+		Definition: regexp.MustCompile(
+			fmt.Sprintf(`^((?:%s\.)*%s)\s{0,1}\{(.*)\}$`, NamespaceExprV2, NameExprV2),
+		),
+		Argument: regexp.MustCompile(
+			fmt.Sprintf(
+				`(?P<key>%s)(=("(?P<value>[^"]*)"|(?P<value>[^%s]*)))?%s?`,
+				NameExprV2,
+				Separator,
+				Separator,
+			),
+		),
+	}
+)
+
+const (
+	// NameExprV2 is the expression of an annotation name (last identifier segment) and attribute key in GrammarV2
+	NameExprV2 = `[\p{L}_][\p{L}\p{N}_]*`
+	// NamespaceExprV2 is the expression of an annotation namespace segment in GrammarV2
+	NamespaceExprV2 = `[\p{L}_][\p{L}\p{N}_-]*`
+)
+
+// Grammars contains all grammar versions by version number
+var Grammars = map[int]Grammar{
+	GrammarV1.Version: GrammarV1,
+	GrammarV2.Version: GrammarV2,
+}
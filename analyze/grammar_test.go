@@ -0,0 +1,88 @@
+package analyze
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/troublete/go-annotation/inspect"
+)
+
+func Test_GrammarV2(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		for _, tc := range []struct {
+			c string
+			d Definition
+		}{
+			{
+				`v2.route{}`,
+				Definition{Identifier: "v2.route", Arguments: map[string]string{}},
+			},
+			{
+				fmt.Sprintf(`limit_10s{max_len2=5%srate}`, Separator),
+				Definition{Identifier: "limit_10s", Arguments: map[string]string{"max_len2": "5", "rate": TrueString}},
+			},
+			{
+				`my-org.api.route{method=GET}`,
+				Definition{Identifier: "my-org.api.route", Arguments: map[string]string{"method": "GET"}},
+			},
+			{
+				`größe.prüfung{länge=5}`,
+				Definition{Identifier: "größe.prüfung", Arguments: map[string]string{"länge": "5"}},
+			},
+		} {
+			t.Run(tc.c, func(t *testing.T) {
+				defs, warnings := Analyzer{Grammar: GrammarV2}.ExtractDefinitions(inspect.Function{
+					Comments: []string{tc.c},
+				})
+
+				if len(warnings) > 0 {
+					t.Errorf("expected no warnings, got %v", warnings)
+				}
+
+				if len(defs) != 1 || defs[0].Identifier != tc.d.Identifier || len(defs[0].Arguments) != len(tc.d.Arguments) {
+					t.Fatalf("definition didn't match (has=%v, want=%v)", defs, tc.d)
+				}
+
+				for k, v := range tc.d.Arguments {
+					if defs[0].Arguments[k] != v {
+						t.Errorf("argument didn't match (has=%v, want=%v)", k+":"+defs[0].Arguments[k], k+":"+v)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		for _, c := range []string{
+			`2route{}`,
+			`route-name{}`,
+			`.route{}`,
+			`route.{}`,
+			`my-org.route{2key=value}`,
+		} {
+			t.Run(c, func(t *testing.T) {
+				defs, warnings := Analyzer{Grammar: GrammarV2}.ExtractDefinitions(inspect.Function{
+					Comments: []string{c},
+				})
+
+				if len(warnings) != 1 {
+					t.Errorf("expected warning, got %v", warnings)
+				}
+
+				if len(defs) > 0 {
+					t.Error("didn't expect definitions")
+				}
+			})
+		}
+	})
+
+	t.Run("v1 default", func(t *testing.T) {
+		_, warnings := Analyzer{}.ExtractDefinitions(inspect.Function{
+			Comments: []string{`v2.route{}`},
+		})
+
+		if len(warnings) != 1 {
+			t.Errorf("expected warning, got %v", warnings)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package analyze
+
+// JSONSchemaDraft is the JSON Schema dialect of all schemas exported
+const JSONSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// valuePatterns restricts the string representation of attribute values by value type
+var valuePatterns = map[ValueType]string{
+	ValueInt:      `^[+-]?[0-9]+$`,
+	ValueFloat:    `^[+-]?([0-9]+(\.[0-9]*)?|\.[0-9]+)([eE][+-]?[0-9]+)?$`,
+	ValueBool:     `^(1|t|T|TRUE|true|True|0|f|F|FALSE|false|False)$`,
+	ValueDuration: `^[+-]?(0|([0-9]+(\.[0-9]*)?(ns|us|µs|μs|ms|s|m|h))+)$`,
+}
+
+// JSONSchema returns the JSON Schema of a definition of the annotation, as found in the inspect output; attribute
+// values are always strings, value types are expressed as pattern and in the x-value-type extension
+func (s Schema) JSONSchema() map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+	for _, a := range s.Attributes {
+		vt := a.Type
+		if vt == "" {
+			vt = ValueString
+		}
+
+		p := map[string]any{
+			"type":         "string",
+			"x-value-type": string(vt),
+		}
+		if pattern, ok := valuePatterns[vt]; ok {
+			p["pattern"] = pattern
+		}
+		if a.Doc != "" {
+			p["description"] = a.Doc
+		}
+		if a.Default != "" {
+			p["default"] = a.Default
+		}
+		properties[a.Name] = p
+
+		if a.Required {
+			required = append(required, a.Name)
+		}
+	}
+
+	arguments := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": s.AdditionalAttributes,
+	}
+	if s.AdditionalAttributes {
+		arguments["additionalProperties"] = map[string]any{"type": "string"}
+	}
+	if len(required) > 0 {
+		arguments["required"] = required
+	}
+
+	targets := []string{}
+	for _, t := range s.Targets {
+		targets = append(targets, string(t))
+	}
+
+	js := map[string]any{
+		"title": s.Identifier,
+		"type":  "object",
+		"properties": map[string]any{
+			"identifier": map[string]any{"const": s.Identifier},
+			"arguments":  arguments,
+			"source":     map[string]any{"enum": []string{SourceDoc, SourceLine}},
+		},
+		"required":     []string{"identifier", "arguments"},
+		"x-targets":    targets,
+		"x-repeatable": s.Repeatable,
+	}
+	if s.Doc != "" {
+		js["description"] = s.Doc
+	}
+	return js
+}
+
+// JSONSchema returns a JSON Schema document validating a definition of any annotation registered; the schema of every
+// annotation is available in $defs by identifier
+func (r *Registry) JSONSchema() map[string]any {
+	defs := map[string]any{}
+	var refs []any
+	for _, s := range r.Schemas() {
+		defs[s.Identifier] = s.JSONSchema()
+		refs = append(refs, map[string]any{"$ref": "#/$defs/" + s.Identifier})
+	}
+
+	js := map[string]any{
+		"$schema": JSONSchemaDraft,
+		"title":   "annotation",
+		"$defs":   defs,
+	}
+	if len(refs) > 0 {
+		js["oneOf"] = refs
+	}
+	return js
+}
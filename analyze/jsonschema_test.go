@@ -0,0 +1,59 @@
+package analyze
+
+import (
+	"regexp"
+	"testing"
+)
+
+func Test_SchemaJSONSchema(t *testing.T) {
+	r := testRegistry(t)
+	s, _ := r.Lookup("crud.field")
+	js := s.JSONSchema()
+
+	if js["title"] != "crud.field" {
+		t.Errorf("unexpected title %v", js["title"])
+	}
+
+	arguments := js["properties"].(map[string]any)["arguments"].(map[string]any)
+	if required := arguments["required"].([]string); len(required) != 1 || required[0] != "name" {
+		t.Errorf("unexpected required attributes %v", required)
+	}
+	if arguments["additionalProperties"] != false {
+		t.Errorf("expected additional attributes to be forbidden")
+	}
+
+	properties := arguments["properties"].(map[string]any)
+	nullable := properties["nullable"].(map[string]any)
+	if nullable["default"] != "false" || nullable["x-value-type"] != "bool" {
+		t.Errorf("unexpected property %v", nullable)
+	}
+	if _, ok := properties["name"].(map[string]any)["pattern"]; ok {
+		t.Errorf("expected no pattern on string attribute")
+	}
+}
+
+func Test_SchemaJSONSchemaPatterns(t *testing.T) {
+	for vt, pattern := range valuePatterns {
+		re := regexp.MustCompile(pattern)
+		for _, v := range []string{"1", "-12", "1.5", "true", "F", "10s", "1h30m", "0", "abc", "1.2.3", ""} {
+			_, err := vt.Parse(v)
+			if re.MatchString(v) != (err == nil) {
+				t.Errorf("pattern of %v disagrees with parsing '%v' (err=%v)", vt, v, err)
+			}
+		}
+	}
+}
+
+func Test_RegistryJSONSchema(t *testing.T) {
+	js := testRegistry(t).JSONSchema()
+
+	if js["$schema"] != JSONSchemaDraft {
+		t.Errorf("unexpected dialect %v", js["$schema"])
+	}
+	if defs := js["$defs"].(map[string]any); len(defs) != 3 {
+		t.Errorf("expected all schemas in $defs, got %v", len(defs))
+	}
+	if refs := js["oneOf"].([]any); len(refs) != 3 || refs[0].(map[string]any)["$ref"] != "#/$defs/crud.field" {
+		t.Errorf("unexpected references %v", refs)
+	}
+}
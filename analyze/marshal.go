@@ -0,0 +1,199 @@
+package analyze
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	keyRe = regexp.MustCompile(fmt.Sprintf(`^(?:[a-zA-Z_]+|%s)$`, NameExprV2))
+
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// FormatValue returns the value as written in an attribute, quoted only if needed (it contains the separator, starts
+// with a quote or has surrounding whitespace); values which need quoting but contain a quote can't be written
+func FormatValue(v string) (string, error) {
+	needsQuotes := strings.Contains(v, Separator) ||
+		strings.HasPrefix(v, `"`) ||
+		strings.TrimFunc(v, unicode.IsSpace) != v
+	if !needsQuotes {
+		return v, nil
+	}
+
+	if strings.Contains(v, `"`) {
+		return "", fmt.Errorf("value '%v' needs quoting but contains a quote", v)
+	}
+	return `"` + v + `"`, nil
+}
+
+// FormatDefinition returns the canonical annotation of the identifier with the arguments in order of keys passed;
+// attributes with the value TRUE are written key-only
+func FormatDefinition(identifier string, keys []string, args map[string]string) (string, error) {
+	if !GrammarV1.Definition.MatchString(identifier+"{}") && !GrammarV2.Definition.MatchString(identifier+"{}") {
+		return "", fmt.Errorf("identifier '%v' is invalid", identifier)
+	}
+
+	attrs, err := formatAttributes(keys, args)
+	if err != nil {
+		return "", err
+	}
+
+	a := fmt.Sprintf("%s{%s}", identifier, strings.Join(attrs, Separator))
+	if len(BracketRe.FindAllString(a, -1))%2 != 0 {
+		return "", fmt.Errorf("annotation '%v' has unbalanced brackets", a)
+	}
+	return a, nil
+}
+
+// formatAttributes returns the attributes of the keys passed as written in an annotation; attributes with the value
+// TRUE are written key-only
+func formatAttributes(keys []string, args map[string]string) ([]string, error) {
+	var attrs []string
+	for _, k := range keys {
+		if !keyRe.MatchString(k) {
+			return nil, fmt.Errorf("attribute key '%v' is invalid", k)
+		}
+
+		v := args[k]
+		if v == TrueString {
+			attrs = append(attrs, k)
+			continue
+		}
+
+		fv, err := FormatValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("attribute '%v': %w", k, err)
+		}
+		attrs = append(attrs, k+"="+fv)
+	}
+	return attrs, nil
+}
+
+// String returns the canonical annotation of the definition, with the attributes sorted by key; if the definition
+// can't be written in valid syntax, the error is returned in place
+func (d Definition) String() string {
+	var keys []string
+	for k := range d.Arguments {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	s, err := FormatDefinition(d.Identifier, keys, d.Arguments)
+	if err != nil {
+		return fmt.Sprintf("%%!(%v)", err)
+	}
+	return s
+}
+
+// Marshal encodes the struct v (or the struct v points to) as annotation with the identifier, the counterpart of
+// Unmarshal; attributes are written in field order, nil pointers and, if tagged with omitempty, zero values are
+// skipped
+func Marshal(identifier string, v any) (string, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return "", fmt.Errorf("marshal source must be a struct, got %T", v)
+	}
+
+	var keys []string
+	args := map[string]string{}
+	for _, f := range structFields(rv.Type()) {
+		fv, ok := valueByIndex(rv, f.index)
+		if !ok || (f.omitEmpty && fv.IsZero()) {
+			continue
+		}
+		if fv.Kind() == reflect.Pointer && fv.IsNil() {
+			continue
+		}
+
+		s, err := formatGoValue(fv)
+		if err != nil {
+			return "", fmt.Errorf("annotation '%v': attribute '%v': %w", identifier, f.key, err)
+		}
+
+		if _, exists := args[f.key]; !exists {
+			keys = append(keys, f.key)
+		}
+		args[f.key] = s
+	}
+
+	return FormatDefinition(identifier, keys, args)
+}
+
+// valueByIndex returns the field by index; false is returned if an embedded struct pointer on the way is nil
+func valueByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for idx, i := range index {
+		if idx > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return v, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v, true
+}
+
+// formatGoValue converts a Go value into its raw attribute value; values reached through unexported embedded structs
+// can't be marshaled by their TextMarshaler, as their methods can't be called
+func formatGoValue(v reflect.Value) (string, error) {
+	marshaler := v.Type().Implements(textMarshalerType) || (v.CanAddr() && v.Addr().Type().Implements(textMarshalerType))
+	if marshaler && !v.CanInterface() {
+		return "", fmt.Errorf("value of type %v is reached through an unexported field and can't be marshaled", v.Type())
+	}
+
+	if v.Type().Implements(textMarshalerType) {
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textMarshalerType) {
+		b, err := v.Addr().Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err
+	}
+
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String(), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		return formatGoValue(v.Elem())
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		if v.Bool() {
+			return TrueString, nil
+		}
+		return "false", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	case reflect.Slice:
+		var parts []string
+		for idx := 0; idx < v.Len(); idx++ {
+			p, err := formatGoValue(v.Index(idx))
+			if err != nil {
+				return "", err
+			}
+			if strings.Contains(p, Separator) {
+				return "", fmt.Errorf("slice value '%v' contains the separator", p)
+			}
+			parts = append(parts, p)
+		}
+		return strings.Join(parts, Separator), nil
+	}
+	return "", fmt.Errorf("unsupported type %v", v.Type())
+}
@@ -0,0 +1,194 @@
+package analyze
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/troublete/go-annotation/inspect"
+)
+
+type testRoute struct {
+	Method   string
+	Path     string
+	Timeout  time.Duration
+	Auth     bool
+	Cache    bool
+	Limit    int     `annotation:",omitempty"`
+	Weight   float64 `annotation:"w,omitempty"`
+	Tags     []string
+	Optional *string
+}
+
+type testPriority string
+
+func (p testPriority) MarshalText() ([]byte, error) {
+	return []byte("priority-" + string(p)), nil
+}
+
+// testLimits and testTimeouts are embedded as unexported pointers
+type testLimits struct {
+	Priority testPriority
+}
+
+type testTimeouts struct {
+	Timeout time.Duration
+}
+
+func Test_Marshal(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		a, err := Marshal("chariot.route", testRoute{
+			Method:  "GET",
+			Path:    "/users/{id}",
+			Timeout: 2 * time.Second,
+			Auth:    true,
+			Tags:    []string{"a", "b"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := `chariot.route{method=GET,path=/users/{id},timeout=2s,auth,cache=false,tags="a,b"}`
+		if a != want {
+			t.Errorf("annotation didn't match (has=%v, want=%v)", a, want)
+		}
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		optional := " spaced "
+		in := testRoute{
+			Method:   "POST",
+			Path:     "/",
+			Timeout:  time.Minute,
+			Cache:    true,
+			Limit:    10,
+			Weight:   0.25,
+			Tags:     []string{"x"},
+			Optional: &optional,
+		}
+
+		a, err := Marshal("chariot.route", &in)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defs, diagnostics := Analyzer{}.ExtractDefinitions(inspect.Function{Comments: []string{a}})
+		if len(diagnostics) > 0 {
+			t.Fatalf("expected no diagnostics, got %v", diagnostics)
+		}
+
+		var out testRoute
+		if err := Unmarshal(defs[0], &out); err != nil {
+			t.Fatal(err)
+		}
+
+		if out.Method != in.Method || out.Path != in.Path || out.Timeout != in.Timeout || out.Auth != in.Auth ||
+			out.Cache != in.Cache || out.Limit != in.Limit || out.Weight != in.Weight ||
+			len(out.Tags) != 1 || out.Tags[0] != "x" || out.Optional == nil || *out.Optional != optional {
+			t.Errorf("round trip failed (in=%+v, out=%+v)", in, out)
+		}
+	})
+
+	t.Run("unexported embedded", func(t *testing.T) {
+		a, err := Marshal("limit", struct {
+			*testLimits
+			Name string
+		}{&testLimits{Priority: "high"}, "a"})
+		if err != nil || a != "limit{priority=priority-high,name=a}" {
+			t.Errorf("unexpected annotation %v (err=%v)", a, err)
+		}
+
+		a, err = Marshal("limit", struct {
+			*testTimeouts
+			Name string
+		}{&testTimeouts{Timeout: time.Second}, "a"})
+		if err != nil || a != "limit{timeout=1s,name=a}" {
+			t.Errorf("unexpected annotation %v (err=%v)", a, err)
+		}
+	})
+
+	t.Run("not interfaceable", func(t *testing.T) {
+		v := reflect.ValueOf(struct {
+			priority testPriority
+			timeout  time.Duration
+		}{"high", time.Second})
+
+		if _, err := formatGoValue(v.Field(0)); err == nil {
+			t.Error("expected error for marshaler which can't be interfaced")
+		}
+		if s, err := formatGoValue(v.Field(1)); err != nil || s != "1s" {
+			t.Errorf("expected duration to be formatted, got %v (err=%v)", s, err)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		for _, tc := range []struct {
+			identifier string
+			v          any
+		}{
+			{"chariot route", testRoute{}},
+			{"chariot.route", "not a struct"},
+			{"chariot.route", testRoute{Path: `"quoted",with separator`}},
+			{"chariot.route", testRoute{Path: "/{unbalanced"}},
+			{"chariot.route", testRoute{Tags: []string{"a,b"}}},
+			{"chariot.route", struct{ M map[string]string }{}},
+		} {
+			if a, err := Marshal(tc.identifier, tc.v); err == nil {
+				t.Errorf("expected error, got %v", a)
+			}
+		}
+	})
+}
+
+func Test_DefinitionString(t *testing.T) {
+	for _, tc := range []struct {
+		d Definition
+		s string
+	}{
+		{
+			Definition{Identifier: "implicit_annotation", Arguments: map[string]string{"read": TrueString, "create": TrueString}},
+			`implicit_annotation{create,read}`,
+		},
+		{
+			Definition{Identifier: "user.custom", Arguments: map[string]string{"test": "c,s,v", "empty": "", "n": "123"}},
+			`user.custom{empty=,n=123,test="c,s,v"}`,
+		},
+		{
+			Definition{Identifier: "user.custom"},
+			`user.custom{}`,
+		},
+		{
+			Definition{Identifier: "user custom"},
+			`%!(identifier 'user custom' is invalid)`,
+		},
+	} {
+		if tc.d.String() != tc.s {
+			t.Errorf("string didn't match (has=%v, want=%v)", tc.d.String(), tc.s)
+		}
+	}
+}
+
+func Test_FormatValue(t *testing.T) {
+	for v, want := range map[string]string{
+		"plain":      "plain",
+		"a,b":        `"a,b"`,
+		" padded":    `" padded"`,
+		`"leading`:   "",
+		`x"y`:        `x"y`,
+		"":           "",
+		"{json:1}":   "{json:1}",
+		`"a,b"`:      "",
+		"with space": "with space",
+	} {
+		has, err := FormatValue(v)
+		if want == "" && v != "" {
+			if err == nil {
+				t.Errorf("expected error for '%v'", v)
+			}
+			continue
+		}
+		if err != nil || has != want {
+			t.Errorf("value didn't match (has=%v, want=%v, err=%v)", has, want, err)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package analyze
+
+import (
+	"strings"
+	"unicode"
+)
+
+// AttributeName derives an attribute key from a Go identifier by converting it to snake case (e.g. `MaxLength` to
+// `max_length`, `ID` to `id`); word separators (`-`, `.`, whitespace) are replaced by underscores, so it converts
+// identifiers like `crud.model` as well
+func AttributeName(name string) string {
+	runes := []rune(name)
+
+	var b strings.Builder
+	for idx, r := range runes {
+		if r == '-' || r == '.' || unicode.IsSpace(r) {
+			runes[idx] = '_'
+			r = '_'
+		}
+		if unicode.IsUpper(r) {
+			prevLower := idx > 0 && (unicode.IsLower(runes[idx-1]) || unicode.IsDigit(runes[idx-1]))
+			nextLower := idx > 0 && idx+1 < len(runes) && unicode.IsLower(runes[idx+1])
+			if idx > 0 && runes[idx-1] != '_' && (prevLower || nextLower) {
+				b.WriteRune('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
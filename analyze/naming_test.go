@@ -0,0 +1,24 @@
+package analyze
+
+import "testing"
+
+func Test_AttributeName(t *testing.T) {
+	for name, want := range map[string]string{
+		"Name":       "name",
+		"MaxLength":  "max_length",
+		"ID":         "id",
+		"UserID":     "user_id",
+		"HTTPServer": "http_server",
+		"Limit10s":   "limit10s",
+		"max_len":    "max_len",
+		"Max_Len":    "max_len",
+		"crud.model": "crud_model",
+		"created at": "created_at",
+		"v2Api":      "v2_api",
+		"":           "",
+	} {
+		if has := AttributeName(name); has != want {
+			t.Errorf("attribute name didn't match (has=%v, want=%v)", has, want)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package analyze
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Target is the kind of spec an annotation is defined on
+type Target string
+
+const (
+	TargetType   Target = "type"
+	TargetField  Target = "field"
+	TargetFunc   Target = "func"
+	TargetMethod Target = "method"
+)
+
+// Targets contains all targets known
+var Targets = []Target{TargetType, TargetField, TargetFunc, TargetMethod}
+
+// ValueType is the type an attribute value must be convertible to
+type ValueType string
+
+const (
+	ValueString   ValueType = "string"
+	ValueInt      ValueType = "int"
+	ValueFloat    ValueType = "float"
+	ValueBool     ValueType = "bool"
+	ValueDuration ValueType = "duration"
+)
+
+// ValueTypes contains all value types known
+var ValueTypes = []ValueType{ValueString, ValueInt, ValueFloat, ValueBool, ValueDuration}
+
+// Parse converts a raw attribute value into a typed value (string, int64, float64, bool or time.Duration); an empty
+// value type is considered a string
+func (vt ValueType) Parse(v string) (any, error) {
+	switch vt {
+	case ValueString, "":
+		return v, nil
+	case ValueInt:
+		return strconv.ParseInt(v, 10, 64)
+	case ValueFloat:
+		return strconv.ParseFloat(v, 64)
+	case ValueBool:
+		return strconv.ParseBool(v)
+	case ValueDuration:
+		return time.ParseDuration(v)
+	}
+	return nil, fmt.Errorf("unknown value type '%v'", vt)
+}
+
+// AttributeSchema describes a single attribute of an annotation
+type AttributeSchema struct {
+	Name     string    `json:"name"`
+	Type     ValueType `json:"type,omitempty"`
+	Required bool      `json:"required,omitempty"`
+	// Default is applied if the attribute is not set; an empty default is considered no default
+	Default string `json:"default,omitempty"`
+	Doc     string `json:"doc,omitempty"`
+}
+
+// Schema describes an annotation, on which targets it can be defined and which attributes it accepts
+type Schema struct {
+	Identifier string `json:"identifier"`
+	Doc        string `json:"doc,omitempty"`
+	// Targets the annotation can be defined on; if empty all targets are allowed
+	Targets    []Target          `json:"targets,omitempty"`
+	Attributes []AttributeSchema `json:"attributes,omitempty"`
+	// Repeatable allows the annotation to be defined multiple times on the same spec; unlike the analyzer, which treats
+	// annotations as repeatable unless declared Unique in its Cardinalities, registering a schema makes the annotation
+	// unique unless Repeatable is set
+	Repeatable bool `json:"repeatable,omitempty"`
+	// AdditionalAttributes allows attributes not described in the schema
+	AdditionalAttributes bool `json:"additional_attributes,omitempty"`
+	// Position is the location the schema is declared at, if it is declared in source
+	Position Position `json:"-"`
+}
+
+// Attribute returns the schema of an attribute by name
+func (s Schema) Attribute(name string) (AttributeSchema, bool) {
+	for _, a := range s.Attributes {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return AttributeSchema{}, false
+}
+
+// Allows checks if the annotation can be defined on the target
+func (s Schema) Allows(target Target) bool {
+	if len(s.Targets) == 0 {
+		return true
+	}
+
+	for _, t := range s.Targets {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	WarnUnknownIdentifier = "annotation '%v' is not registered"
+	WarnTargetNotAllowed  = "annotation '%v' is not allowed on %v"
+	WarnAttributeUnknown  = "attribute '%v' is not defined for annotation '%v'"
+	WarnAttributeMissing  = "attribute '%v' is required for annotation '%v'"
+	WarnAttributeType     = "attribute '%v' of annotation '%v' is not of type %v: %v"
+)
+
+// Registry contains the schemas of all annotations known; the zero value is ready to use
+type Registry struct {
+	mu      sync.RWMutex
+	schemas map[string]Schema
+
+	// AllowUnknown disables diagnostics on annotations not registered
+	AllowUnknown bool
+}
+
+// Register adds the schema of an annotation to the registry; an annotation can only be registered once
+func (r *Registry) Register(s Schema) error {
+	if s.Identifier == "" {
+		return fmt.Errorf("schema identifier is required")
+	}
+
+	names := map[string]bool{}
+	for _, a := range s.Attributes {
+		if names[a.Name] {
+			return fmt.Errorf("attribute '%v' of '%v' is defined more than once", a.Name, s.Identifier)
+		}
+		names[a.Name] = true
+
+		if a.Default != "" {
+			if _, err := a.Type.Parse(a.Default); err != nil {
+				return fmt.Errorf("default of attribute '%v' of '%v' is invalid: %w", a.Name, s.Identifier, err)
+			}
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.schemas == nil {
+		r.schemas = map[string]Schema{}
+	}
+	if _, exists := r.schemas[s.Identifier]; exists {
+		return fmt.Errorf("schema '%v' is already registered", s.Identifier)
+	}
+	r.schemas[s.Identifier] = s
+	return nil
+}
+
+// Lookup returns the schema of an annotation by identifier
+func (r *Registry) Lookup(identifier string) (Schema, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.schemas[identifier]
+	return s, ok
+}
+
+// Schemas returns all schemas registered, sorted by identifier
+func (r *Registry) Schemas() []Schema {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var schemas []Schema
+	for _, s := range r.schemas {
+		schemas = append(schemas, s)
+	}
+	sort.Slice(schemas, func(i, j int) bool {
+		return schemas[i].Identifier < schemas[j].Identifier
+	})
+	return schemas
+}
+
+// Validate checks the definitions of a single spec against the registered schemas; it returns the definitions passing
+// with defaults applied, alongside the diagnostics found. Definitions of unknown annotations are passed through
+func (r *Registry) Validate(target Target, dl DefinitionList) (DefinitionList, Diagnostics) {
+	var valid DefinitionList
+	var diagnostics Diagnostics
+
+	seen := map[string]int{}
+	for _, def := range dl {
+		s, ok := r.Lookup(def.Identifier)
+		if !ok {
+			if !r.AllowUnknown {
+				diagnostics = append(diagnostics, Diagnostic{
+					Severity: SeverityWarning,
+					Code:     CodeUnknownIdentifier,
+					Message:  fmt.Sprintf(WarnUnknownIdentifier, def.Identifier),
+					Position: def.Position,
+				})
+			}
+			valid = append(valid, def)
+			continue
+		}
+
+		ds := s.validate(target, def)
+		seen[def.Identifier]++
+		if !s.Repeatable && seen[def.Identifier] > 1 {
+			ds = append(ds, Diagnostic{
+				Severity:     SeverityError,
+				Code:         CodeDefinitionNotRepeatable,
+				Message:      fmt.Sprintf(WarnDefinitionNotRepeatable, def.Identifier, seen[def.Identifier]),
+				Position:     def.Position,
+				SuggestedFix: fmt.Sprintf("remove the additional definitions of '%v'", def.Identifier),
+			})
+		}
+
+		diagnostics = append(diagnostics, ds...)
+		if len(ds.AtLeast(SeverityError)) > 0 {
+			continue
+		}
+
+		valid = append(valid, s.applyDefaults(def))
+	}
+
+	return valid, diagnostics
+}
+
+// validate checks a single definition against the schema
+func (s Schema) validate(target Target, def Definition) Diagnostics {
+	var diagnostics Diagnostics
+	diagnose := func(code, message, fix string) {
+		diagnostics = append(diagnostics, Diagnostic{
+			Severity:     SeverityError,
+			Code:         code,
+			Message:      message,
+			Position:     def.Position,
+			SuggestedFix: fix,
+		})
+	}
+
+	if !s.Allows(target) {
+		diagnose(CodeTargetNotAllowed, fmt.Sprintf(WarnTargetNotAllowed, def.Identifier, target), "")
+	}
+
+	for _, a := range s.Attributes {
+		v, ok := def.Arguments[a.Name]
+		if !ok {
+			if a.Required {
+				diagnose(
+					CodeAttributeMissing,
+					fmt.Sprintf(WarnAttributeMissing, a.Name, def.Identifier),
+					fmt.Sprintf("add the attribute '%v'", a.Name),
+				)
+			}
+			continue
+		}
+
+		if _, err := a.Type.Parse(v); err != nil {
+			diagnose(CodeAttributeType, fmt.Sprintf(WarnAttributeType, a.Name, def.Identifier, a.Type, err), "")
+		}
+	}
+
+	if !s.AdditionalAttributes {
+		var keys []string
+		for k := range def.Arguments {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			if _, ok := s.Attribute(k); !ok {
+				diagnose(
+					CodeAttributeUnknown,
+					fmt.Sprintf(WarnAttributeUnknown, k, def.Identifier),
+					fmt.Sprintf("remove the attribute '%v'", k),
+				)
+			}
+		}
+	}
+
+	return diagnostics
+}
+
+// applyDefaults returns a copy of the definition with the defaults of all attributes not set applied
+func (s Schema) applyDefaults(def Definition) Definition {
+	args := map[string]string{}
+	for k, v := range def.Arguments {
+		args[k] = v
+	}
+
+	for _, a := range s.Attributes {
+		if _, ok := args[a.Name]; !ok && a.Default != "" {
+			args[a.Name] = a.Default
+		}
+	}
+
+	def.Arguments = args
+	return def
+}
@@ -0,0 +1,178 @@
+package analyze
+
+import (
+	"testing"
+	"time"
+)
+
+func testRegistry(t *testing.T) *Registry {
+	r := &Registry{}
+	for _, s := range []Schema{
+		{
+			Identifier: "crud.model",
+			Targets:    []Target{TargetType},
+			Attributes: []AttributeSchema{
+				{Name: "name", Required: true},
+			},
+		},
+		{
+			Identifier: "crud.field",
+			Targets:    []Target{TargetField},
+			Attributes: []AttributeSchema{
+				{Name: "name", Required: true},
+				{Name: "max_length", Type: ValueInt},
+				{Name: "nullable", Type: ValueBool, Default: "false"},
+			},
+		},
+		{
+			Identifier: "crud.index",
+			Repeatable: true,
+		},
+	} {
+		if err := r.Register(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return r
+}
+
+func Test_RegistryRegister(t *testing.T) {
+	r := testRegistry(t)
+
+	for _, s := range []Schema{
+		{},
+		{Identifier: "crud.model"},
+		{Identifier: "a", Attributes: []AttributeSchema{{Name: "b"}, {Name: "b"}}},
+		{Identifier: "a", Attributes: []AttributeSchema{{Name: "b", Type: ValueInt, Default: "one"}}},
+	} {
+		if err := r.Register(s); err == nil {
+			t.Errorf("expected error registering %v", s)
+		}
+	}
+
+	if _, ok := r.Lookup("crud.model"); !ok {
+		t.Error("expected 'crud.model' to be registered")
+	}
+
+	schemas := r.Schemas()
+	if len(schemas) != 3 || schemas[0].Identifier != "crud.field" || schemas[2].Identifier != "crud.model" {
+		t.Error("expected schemas sorted by identifier")
+	}
+}
+
+func Test_RegistryValidate(t *testing.T) {
+	r := testRegistry(t)
+
+	for _, tc := range []struct {
+		name   string
+		target Target
+		dl     DefinitionList
+		valid  int
+		codes  []string
+	}{
+		{
+			"valid",
+			TargetField,
+			DefinitionList{
+				{Identifier: "crud.field", Arguments: map[string]string{"name": "id", "max_length": "10"}},
+				{Identifier: "crud.index", Arguments: map[string]string{}},
+				{Identifier: "crud.index", Arguments: map[string]string{}},
+			},
+			3,
+			nil,
+		},
+		{
+			"unknown identifier",
+			TargetField,
+			DefinitionList{{Identifier: "crud.unknown", Arguments: map[string]string{}}},
+			1,
+			[]string{CodeUnknownIdentifier},
+		},
+		{
+			"target not allowed",
+			TargetField,
+			DefinitionList{{Identifier: "crud.model", Arguments: map[string]string{"name": "users"}}},
+			0,
+			[]string{CodeTargetNotAllowed},
+		},
+		{
+			"attributes",
+			TargetField,
+			DefinitionList{{Identifier: "crud.field", Arguments: map[string]string{"max_length": "ten", "other": "x"}}},
+			0,
+			[]string{CodeAttributeMissing, CodeAttributeType, CodeAttributeUnknown},
+		},
+		{
+			"not repeatable",
+			TargetType,
+			DefinitionList{
+				{Identifier: "crud.model", Arguments: map[string]string{"name": "users"}},
+				{Identifier: "crud.model", Arguments: map[string]string{"name": "accounts"}},
+			},
+			1,
+			[]string{CodeDefinitionNotRepeatable},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			valid, diagnostics := r.Validate(tc.target, tc.dl)
+			if len(valid) != tc.valid {
+				t.Errorf("expected %v valid definitions, got %v", tc.valid, len(valid))
+			}
+
+			if len(diagnostics) != len(tc.codes) {
+				t.Fatalf("expected diagnostics %v, got %v", tc.codes, diagnostics)
+			}
+
+			for idx, d := range diagnostics {
+				if d.Code != tc.codes[idx] {
+					t.Errorf("expected diagnostic %v, got %v", tc.codes[idx], d.Code)
+				}
+			}
+		})
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		args := map[string]string{"name": "id"}
+		valid, _ := r.Validate(TargetField, DefinitionList{{Identifier: "crud.field", Arguments: args}})
+		if valid[0].Arguments["nullable"] != "false" {
+			t.Error("expected default to be applied")
+		}
+		if _, ok := args["nullable"]; ok {
+			t.Error("expected arguments passed to be untouched")
+		}
+	})
+
+	t.Run("allow unknown", func(t *testing.T) {
+		r := &Registry{AllowUnknown: true}
+		_, diagnostics := r.Validate(TargetFunc, DefinitionList{{Identifier: "chariot.route"}})
+		if len(diagnostics) > 0 {
+			t.Error("expected no diagnostics")
+		}
+	})
+}
+
+func Test_ValueTypeParse(t *testing.T) {
+	for _, tc := range []struct {
+		vt  ValueType
+		v   string
+		out any
+	}{
+		{"", "a", "a"},
+		{ValueString, "a", "a"},
+		{ValueInt, "-12", int64(-12)},
+		{ValueFloat, "1.5", 1.5},
+		{ValueBool, TrueString, true},
+		{ValueDuration, "1m", time.Minute},
+	} {
+		out, err := tc.vt.Parse(tc.v)
+		if err != nil || out != tc.out {
+			t.Errorf("failed to parse '%v' as %v", tc.v, tc.vt)
+		}
+	}
+
+	for _, vt := range []ValueType{ValueInt, ValueFloat, ValueBool, ValueDuration, "unknown"} {
+		if _, err := vt.Parse("x"); err == nil {
+			t.Errorf("expected %v parse error", vt)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package analyze
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TagName is the struct tag used to map struct fields to attribute keys (e.g. `annotation:"max_length"`); fields
+// without tag are mapped to the snake cased field name, fields tagged with "-" are skipped
+const TagName = "annotation"
+
+// ErrDefinitionNotFound is returned when decoding an annotation not defined
+var ErrDefinitionNotFound = errors.New("annotation not defined")
+
+// UnmarshalError is returned if an attribute can't be converted into the type of the struct field it is mapped to
+type UnmarshalError struct {
+	Identifier string
+	Attribute  string
+	Value      string
+	Err        error
+}
+
+func (e *UnmarshalError) Error() string {
+	return fmt.Sprintf("annotation '%v': attribute '%v' (value '%v'): %v", e.Identifier, e.Attribute, e.Value, e.Err)
+}
+
+func (e *UnmarshalError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// structField is a struct field mapped to an attribute key
+type structField struct {
+	key       string
+	omitEmpty bool
+	index     []int
+}
+
+// structFields returns all exported fields of a struct type mapped to their attribute keys; fields of embedded
+// structs are flattened
+func structFields(t reflect.Type) []structField {
+	var fields []structField
+	for idx := 0; idx < t.NumField(); idx++ {
+		f := t.Field(idx)
+		tag, hasTag := f.Tag.Lookup(TagName)
+		if tag == "-" {
+			continue
+		}
+
+		if f.Anonymous && !hasTag {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for _, ef := range structFields(ft) {
+					ef.index = append([]int{idx}, ef.index...)
+					fields = append(fields, ef)
+				}
+				continue
+			}
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = AttributeName(f.Name)
+		}
+
+		fields = append(fields, structField{
+			key:       name,
+			omitEmpty: opts == "omitempty",
+			index:     []int{idx},
+		})
+	}
+	return fields
+}
+
+// Unmarshal fills the struct v points to with the attributes of the definition; attributes are converted into the
+// type of the field they are mapped to (strings, ints, uints, floats, bools, time.Duration, types implementing
+// encoding.TextUnmarshaler, pointers of those and slices of those with values separated by the separator)
+func Unmarshal(def Definition, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("unmarshal target must be a non-nil pointer to a struct, got %T", v)
+	}
+	rv = rv.Elem()
+
+	for _, f := range structFields(rv.Type()) {
+		raw, ok := def.Arguments[f.key]
+		if !ok {
+			continue
+		}
+
+		fv, err := fieldByIndex(rv, f.index)
+		if err == nil {
+			err = setValue(fv, raw)
+		}
+		if err != nil {
+			return &UnmarshalError{
+				Identifier: def.Identifier,
+				Attribute:  f.key,
+				Value:      raw,
+				Err:        err,
+			}
+		}
+	}
+	return nil
+}
+
+// Decode unmarshals the definitions with the identifier into v; if v points to a struct, the first definition is
+// decoded (ErrDefinitionNotFound is returned if there is none), if v points to a slice of structs, all definitions
+// are decoded and appended
+func (dl DefinitionList) Decode(identifier string, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("decode target must be a non-nil pointer, got %T", v)
+	}
+
+	if rv.Elem().Kind() == reflect.Slice {
+		sv := rv.Elem()
+		for _, def := range dl {
+			if def.Identifier != identifier {
+				continue
+			}
+
+			ev := reflect.New(sv.Type().Elem())
+			if err := Unmarshal(def, ev.Interface()); err != nil {
+				return err
+			}
+			sv.Set(reflect.Append(sv, ev.Elem()))
+		}
+		return nil
+	}
+
+	for _, def := range dl {
+		if def.Identifier == identifier {
+			return Unmarshal(def, v)
+		}
+	}
+	return fmt.Errorf("%w: '%v'", ErrDefinitionNotFound, identifier)
+}
+
+// fieldByIndex returns the field by index, allocating embedded struct pointers on the way
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, error) {
+	for idx, i := range index {
+		if idx > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return v, fmt.Errorf("can't set embedded pointer of unexported type %v", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v, nil
+}
+
+// setValue converts the raw attribute value into the type of v and sets it
+func setValue(v reflect.Value, raw string) error {
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw))
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		pv := reflect.New(v.Type().Elem())
+		if err := setValue(pv.Elem(), raw); err != nil {
+			return err
+		}
+		v.Set(pv)
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var parts []string
+		if raw != "" {
+			parts = strings.Split(raw, Separator)
+		}
+
+		sv := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for idx, p := range parts {
+			if err := setValue(sv.Index(idx), strings.TrimSpace(p)); err != nil {
+				return err
+			}
+		}
+		v.Set(sv)
+	default:
+		return fmt.Errorf("unsupported type %v", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package analyze
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testLevel int
+
+func (l *testLevel) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return fmt.Errorf("unknown level '%s'", b)
+	}
+	return nil
+}
+
+type testEmbedded struct {
+	Table string
+}
+
+type testField struct {
+	testEmbedded
+	Name      string
+	MaxLength int `annotation:"max_len"`
+	Primary   bool
+	Ratio     float64
+	Size      uint8
+	Timeout   time.Duration
+	Tags      []string
+	Ports     []int
+	Nullable  *bool
+	Level     testLevel
+	Ignored   string `annotation:"-"`
+	internal  string
+}
+
+func Test_Unmarshal(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		var f testField
+		err := Unmarshal(Definition{
+			Identifier: "crud.field",
+			Arguments: map[string]string{
+				"table":    "users",
+				"name":     "id",
+				"max_len":  "10",
+				"primary":  TrueString,
+				"ratio":    "0.5",
+				"size":     "8",
+				"timeout":  "1m30s",
+				"tags":     "a, b,c",
+				"ports":    "80,443",
+				"nullable": "false",
+				"level":    "high",
+				"ignored":  "value",
+				"internal": "value",
+				"unknown":  "value",
+			},
+		}, &f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if f.Table != "users" ||
+			f.Name != "id" ||
+			f.MaxLength != 10 ||
+			!f.Primary ||
+			f.Ratio != 0.5 ||
+			f.Size != 8 ||
+			f.Timeout != 90*time.Second ||
+			strings.Join(f.Tags, "|") != "a|b|c" ||
+			len(f.Ports) != 2 || f.Ports[1] != 443 ||
+			f.Nullable == nil || *f.Nullable ||
+			f.Level != 2 ||
+			f.Ignored != "" ||
+			f.internal != "" {
+			t.Errorf("unexpected result %+v", f)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		for key, value := range map[string]string{
+			"max_len": "ten",
+			"primary": "maybe",
+			"size":    "256",
+			"timeout": "forever",
+			"ports":   "80,http",
+			"level":   "medium",
+		} {
+			var f testField
+			err := Unmarshal(Definition{Identifier: "crud.field", Arguments: map[string]string{key: value}}, &f)
+
+			var ue *UnmarshalError
+			if !errors.As(err, &ue) || ue.Attribute != key || ue.Identifier != "crud.field" {
+				t.Errorf("expected unmarshal error naming '%v', got %v", key, err)
+			}
+		}
+	})
+
+	t.Run("invalid target", func(t *testing.T) {
+		var f testField
+		for _, v := range []any{nil, f, new(string), (*testField)(nil)} {
+			if err := Unmarshal(Definition{}, v); err == nil {
+				t.Errorf("expected error for %T", v)
+			}
+		}
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		var v struct {
+			M map[string]string
+		}
+		if err := Unmarshal(Definition{Arguments: map[string]string{"m": "x"}}, &v); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func Test_DefinitionListDecode(t *testing.T) {
+	dl := DefinitionList{
+		{Identifier: "crud.index", Arguments: map[string]string{"name": "a"}},
+		{Identifier: "crud.field", Arguments: map[string]string{"name": "id"}},
+		{Identifier: "crud.index", Arguments: map[string]string{"name": "b"}},
+	}
+
+	var f testField
+	if err := dl.Decode("crud.field", &f); err != nil || f.Name != "id" {
+		t.Errorf("expected field to be decoded (err=%v)", err)
+	}
+
+	var indexes []testField
+	if err := dl.Decode("crud.index", &indexes); err != nil || len(indexes) != 2 || indexes[1].Name != "b" {
+		t.Errorf("expected indexes to be decoded (err=%v)", err)
+	}
+
+	if err := dl.Decode("crud.model", &f); !errors.Is(err, ErrDefinitionNotFound) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+
+	if err := dl.Decode("crud.field", f); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,274 @@
+package annotation
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/troublete/go-annotation/analyze"
+	"github.com/troublete/go-annotation/inspect"
+)
+
+const (
+	CodeConstraintRequires    = "constraint-requires"
+	CodeConstraintExcludes    = "constraint-excludes"
+	CodeConstraintParent      = "constraint-parent"
+	CodeConstraintCardinality = "constraint-cardinality"
+)
+
+// Constraint checks relations between annotations of related specs (type, fields and methods) in a result
+type Constraint interface {
+	Check(r *Result) analyze.Diagnostics
+}
+
+// ConstraintFunc implements a constraint with a function
+type ConstraintFunc func(r *Result) analyze.Diagnostics
+
+func (f ConstraintFunc) Check(r *Result) analyze.Diagnostics {
+	return f(r)
+}
+
+// Matcher matches annotations by identifier and, optionally, by attribute; if only the attribute is set it matches if
+// present and not false (so flags like `primary` work with a schema default of false), otherwise its value is matched
+type Matcher struct {
+	Identifier string
+	Attribute  string
+	Value      string
+}
+
+// Match checks if the definition matches
+func (m Matcher) Match(d analyze.Definition) bool {
+	if d.Identifier != m.Identifier {
+		return false
+	}
+	if m.Attribute == "" {
+		return true
+	}
+
+	v, ok := d.Arguments[m.Attribute]
+	if !ok {
+		return false
+	}
+	if m.Value == "" {
+		b, err := analyze.ValueBool.Parse(v)
+		return err != nil || b.(bool)
+	}
+	return v == m.Value
+}
+
+func (m Matcher) String() string {
+	switch {
+	case m.Attribute == "":
+		return m.Identifier
+	case m.Value == "":
+		return fmt.Sprintf("%s[%s]", m.Identifier, m.Attribute)
+	}
+	return fmt.Sprintf("%s[%s=%s]", m.Identifier, m.Attribute, m.Value)
+}
+
+// annotatedSpec is any annotated spec of a result, alongside the type it belongs to (fields and methods)
+type annotatedSpec struct {
+	target      analyze.Target
+	reference   string
+	annotations definitions
+	parent      *AnnotatedType
+}
+
+// specs returns all annotated specs of the result; types first, followed by their fields, then functions
+func (r *Result) specs() []annotatedSpec {
+	var specs []annotatedSpec
+	for idx := range r.Types {
+		at := &r.Types[idx]
+		specs = append(specs, annotatedSpec{
+			target:      analyze.TargetType,
+			reference:   TypeReference(at.Type),
+			annotations: definitions(at.Annotations),
+		})
+
+		for _, af := range at.Fields {
+			specs = append(specs, annotatedSpec{
+				target:      analyze.TargetField,
+				reference:   FieldReference(at.Type, af.Field),
+				annotations: definitions(af.Annotations),
+				parent:      at,
+			})
+		}
+	}
+
+	for _, af := range r.Functions {
+		specs = append(specs, annotatedSpec{
+			target:      FunctionTarget(af.Function),
+			reference:   FunctionReference(af.Function),
+			annotations: definitions(af.Annotations),
+			parent:      r.ReceiverType(af.Function),
+		})
+	}
+	return specs
+}
+
+// ReceiverType returns the annotated type a function is defined on, or nil if it is no method or the type is not part
+// of the result; types are matched by name, package and directory
+func (r *Result) ReceiverType(f inspect.Function) *AnnotatedType {
+	if f.Receiver == nil {
+		return nil
+	}
+
+	for idx, at := range r.Types {
+		if at.Type.Name == f.Receiver.ReceiverType &&
+			at.Type.Package == f.Package &&
+			filepath.Dir(at.Type.FilePath) == filepath.Dir(f.FilePath) {
+			return &r.Types[idx]
+		}
+	}
+	return nil
+}
+
+// diagnostic creates a constraint violation of a definition on a spec
+func (s annotatedSpec) diagnostic(d analyze.Definition, code, message string) analyze.Diagnostic {
+	return analyze.Diagnostic{
+		Severity: analyze.SeverityError,
+		Code:     code,
+		Message:  message,
+		Position: d.Position,
+		Spec:     s.reference,
+	}
+}
+
+// Requires constrains specs annotated with identifier to also be annotated with other
+func Requires(identifier, other Matcher) Constraint {
+	return ConstraintFunc(func(r *Result) analyze.Diagnostics {
+		var diagnostics analyze.Diagnostics
+		for _, s := range r.specs() {
+			for _, d := range s.annotations {
+				if identifier.Match(d) && !s.annotations.any(other) {
+					diagnostics = append(diagnostics, s.diagnostic(d, CodeConstraintRequires, fmt.Sprintf(
+						"annotation '%v' requires '%v' on the same spec", identifier, other,
+					)))
+				}
+			}
+		}
+		return diagnostics
+	})
+}
+
+// Excludes constrains specs annotated with identifier to not be annotated with other
+func Excludes(identifier, other Matcher) Constraint {
+	return ConstraintFunc(func(r *Result) analyze.Diagnostics {
+		var diagnostics analyze.Diagnostics
+		for _, s := range r.specs() {
+			for _, d := range s.annotations {
+				if identifier.Match(d) && s.annotations.any(other) {
+					diagnostics = append(diagnostics, s.diagnostic(d, CodeConstraintExcludes, fmt.Sprintf(
+						"annotation '%v' excludes '%v' on the same spec", identifier, other,
+					)))
+				}
+			}
+		}
+		return diagnostics
+	})
+}
+
+// RequiresParent constrains fields and methods annotated with identifier to belong to a type annotated with parent
+// (e.g. `crud.field` is only allowed on fields of a type annotated `crud.model`)
+func RequiresParent(identifier, parent Matcher) Constraint {
+	return ConstraintFunc(func(r *Result) analyze.Diagnostics {
+		var diagnostics analyze.Diagnostics
+		for _, s := range r.specs() {
+			if s.target != analyze.TargetField && s.target != analyze.TargetMethod {
+				continue
+			}
+
+			for _, d := range s.annotations {
+				if identifier.Match(d) && (s.parent == nil || !definitions(s.parent.Annotations).any(parent)) {
+					diagnostics = append(diagnostics, s.diagnostic(d, CodeConstraintParent, fmt.Sprintf(
+						"annotation '%v' is only allowed on %vs of types annotated '%v'", identifier, s.target, parent,
+					)))
+				}
+			}
+		}
+		return diagnostics
+	})
+}
+
+// Cardinality constrains the number of fields or methods (target) with an annotation matching within each type
+// annotated with parent to [min, max]; a negative max is unbounded (e.g. `primary` may appear on exactly one field per `crud.model`)
+func Cardinality(parent Matcher, target analyze.Target, m Matcher, min, max int) Constraint {
+	return ConstraintFunc(func(r *Result) analyze.Diagnostics {
+		counts := map[*AnnotatedType]int{}
+		positions := map[*AnnotatedType]analyze.Position{}
+		for _, s := range r.specs() {
+			if s.target != target || s.parent == nil {
+				continue
+			}
+
+			// a spec counts once, no matter how many of its annotations match
+			if d, ok := definitions(s.annotations).first(m); ok {
+				counts[s.parent]++
+				if counts[s.parent] == max+1 {
+					positions[s.parent] = d.Position
+				}
+			}
+		}
+
+		var diagnostics analyze.Diagnostics
+		for idx := range r.Types {
+			at := &r.Types[idx]
+			pd, ok := definitions(at.Annotations).first(parent)
+			if !ok {
+				continue
+			}
+
+			n := counts[at]
+			if n >= min && (max < 0 || n <= max) {
+				continue
+			}
+
+			position := pd.Position
+			if p, ok := positions[at]; ok {
+				position = p
+			}
+
+			bounds := fmt.Sprintf("between %v and %v", min, max)
+			if max < 0 {
+				bounds = fmt.Sprintf("at least %v", min)
+			}
+
+			diagnostics = append(diagnostics, analyze.Diagnostic{
+				Severity: analyze.SeverityError,
+				Code:     CodeConstraintCardinality,
+				Message: fmt.Sprintf(
+					"type annotated '%v' has %v %vs annotated '%v', expected %v", parent, n, target, m, bounds,
+				),
+				Position: position,
+				Spec:     TypeReference(at.Type),
+			})
+		}
+		return diagnostics
+	})
+}
+
+// CheckConstraints checks all constraints on the result
+func CheckConstraints(r *Result, cs ...Constraint) analyze.Diagnostics {
+	var diagnostics analyze.Diagnostics
+	for _, c := range cs {
+		diagnostics = append(diagnostics, c.Check(r)...)
+	}
+	return diagnostics
+}
+
+type definitions analyze.DefinitionList
+
+// any checks if any definition matches
+func (dl definitions) any(m Matcher) bool {
+	_, ok := dl.first(m)
+	return ok
+}
+
+// first returns the first definition matching
+func (dl definitions) first(m Matcher) (analyze.Definition, bool) {
+	for _, d := range dl {
+		if m.Match(d) {
+			return d, true
+		}
+	}
+	return analyze.Definition{}, false
+}
@@ -0,0 +1,195 @@
+package annotation
+
+import (
+	"testing"
+
+	"github.com/troublete/go-annotation/analyze"
+	"github.com/troublete/go-annotation/inspect"
+)
+
+func testConstraintResult(t *testing.T) *Result {
+	r, err := Read(inspect.TypeList{
+		{
+			Name:     "User",
+			Package:  "model",
+			FilePath: "model/user.go",
+			Comments: []string{`crud.model{name=users}`},
+			Fields: []inspect.Field{
+				{Name: "ID", Comments: []string{`crud.field{name=id,primary}`}},
+				{Name: "Name", Comments: []string{`crud.field{name=name}`, `crud.index{}`}},
+			},
+		},
+		{
+			Name:     "Account",
+			Package:  "model",
+			FilePath: "model/account.go",
+			Comments: []string{`crud.model{name=accounts}`},
+			Fields: []inspect.Field{
+				{Name: "ID", Comments: []string{`crud.field{name=id,primary}`}},
+				{Name: "UserID", Comments: []string{`crud.field{name=user_id,primary}`}},
+			},
+		},
+		{
+			Name:     "Session",
+			Package:  "model",
+			FilePath: "model/session.go",
+			Fields: []inspect.Field{
+				{Name: "Token", Comments: []string{`crud.field{name=token}`}},
+			},
+		},
+	}, inspect.FunctionList{
+		{Name: "Save", Package: "model", FilePath: "model/user.go", Receiver: &inspect.Receiver{ReceiverType: "User"}, Comments: []string{`crud.hook{}`}},
+		{Name: "Touch", Package: "model", FilePath: "model/session.go", Receiver: &inspect.Receiver{ReceiverType: "Session"}, Comments: []string{`crud.hook{}`}},
+		{Name: "Save", Package: "other", FilePath: "other/user.go", Receiver: &inspect.Receiver{ReceiverType: "User"}, Comments: []string{`crud.hook{}`}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func Test_Constraints(t *testing.T) {
+	model := Matcher{Identifier: "crud.model"}
+	field := Matcher{Identifier: "crud.field"}
+
+	for _, tc := range []struct {
+		name  string
+		c     Constraint
+		specs []string
+	}{
+		{
+			"requires parent field",
+			RequiresParent(field, model),
+			[]string{"model.Session.Token"},
+		},
+		{
+			"requires parent method",
+			RequiresParent(Matcher{Identifier: "crud.hook"}, model),
+			[]string{"model.Session.Touch", "other.User.Save"},
+		},
+		{
+			"requires",
+			Requires(Matcher{Identifier: "crud.index"}, Matcher{Identifier: "crud.field", Attribute: "primary"}),
+			[]string{"model.User.Name"},
+		},
+		{
+			"excludes",
+			Excludes(Matcher{Identifier: "crud.field", Attribute: "primary"}, Matcher{Identifier: "crud.index"}),
+			nil,
+		},
+		{
+			"excludes violated",
+			Excludes(Matcher{Identifier: "crud.field", Attribute: "name", Value: "name"}, Matcher{Identifier: "crud.index"}),
+			[]string{"model.User.Name"},
+		},
+		{
+			"cardinality",
+			Cardinality(model, analyze.TargetField, Matcher{Identifier: "crud.field", Attribute: "primary"}, 1, 1),
+			[]string{"model.Account"},
+		},
+		{
+			"cardinality unbounded",
+			Cardinality(model, analyze.TargetMethod, Matcher{Identifier: "crud.hook"}, 1, -1),
+			[]string{"model.Account"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			diagnostics := CheckConstraints(testConstraintResult(t), tc.c)
+			if len(diagnostics) != len(tc.specs) {
+				t.Fatalf("expected violations on %v, got %v", tc.specs, diagnostics)
+			}
+
+			for idx, d := range diagnostics {
+				if d.Spec != tc.specs[idx] || d.Severity != analyze.SeverityError {
+					t.Errorf("unexpected diagnostic %v", d)
+				}
+			}
+		})
+	}
+}
+
+func Test_CardinalityCountsSpecs(t *testing.T) {
+	r, err := Read(inspect.TypeList{
+		{
+			Name:     "User",
+			Package:  "model",
+			Comments: []string{`crud.model{name=users}`},
+			Fields: []inspect.Field{
+				{Name: "ID", Comments: []string{`crud.index{name=a}`, `crud.index{name=b}`}},
+				{Name: "Name"},
+			},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := Cardinality(Matcher{Identifier: "crud.model"}, analyze.TargetField, Matcher{Identifier: "crud.index"}, 1, 1)
+	if diagnostics := CheckConstraints(r, c); len(diagnostics) > 0 {
+		t.Errorf("expected a field with two matching annotations to count once, got %v", diagnostics)
+	}
+}
+
+func Test_CardinalityWithSchemaDefault(t *testing.T) {
+	registry := &analyze.Registry{}
+	err := registry.Register(analyze.Schema{
+		Identifier: "crud.field",
+		Attributes: []analyze.AttributeSchema{
+			{Name: "name", Required: true},
+			{Name: "primary", Type: analyze.ValueBool, Default: "false"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := ReadWithOptions(inspect.TypeList{
+		{
+			Name:     "User",
+			Package:  "model",
+			Comments: []string{`crud.model{name=users}`},
+			Fields: []inspect.Field{
+				{Name: "ID", Comments: []string{`crud.field{name=id,primary}`}},
+				{Name: "Name", Comments: []string{`crud.field{name=name}`}},
+				{Name: "Email", Comments: []string{`crud.field{name=email,primary=FALSE}`}},
+			},
+		},
+	}, nil, Options{Registry: registry, Strictness: NeverFail})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := r.Types[0].Fields[1].Annotations[0].Arguments["primary"]; v != "false" {
+		t.Fatalf("expected default to be applied, got '%v'", v)
+	}
+
+	primary := Matcher{Identifier: "crud.field", Attribute: "primary"}
+	c := Cardinality(Matcher{Identifier: "crud.model"}, analyze.TargetField, primary, 1, 1)
+	if diagnostics := CheckConstraints(r, c); len(diagnostics) > 0 {
+		t.Errorf("expected attributes set to false not to match, got %v", diagnostics)
+	}
+}
+
+func Test_ReadConstraints(t *testing.T) {
+	_, err := ReadWithOptions(inspect.TypeList{
+		{Fields: []inspect.Field{{Comments: []string{`crud.field{}`}}}},
+	}, nil, Options{
+		Constraints: []Constraint{
+			RequiresParent(Matcher{Identifier: "crud.field"}, Matcher{Identifier: "crud.model"}),
+		},
+	})
+	if err == nil {
+		t.Error("expected constraint to fail reading")
+	}
+}
+
+func Test_MatcherString(t *testing.T) {
+	for m, s := range map[Matcher]string{
+		{Identifier: "crud.field"}:                                 "crud.field",
+		{Identifier: "crud.field", Attribute: "primary"}:           "crud.field[primary]",
+		{Identifier: "crud.field", Attribute: "name", Value: "id"}: "crud.field[name=id]",
+	} {
+		if m.String() != s {
+			t.Errorf("string didn't match (has=%v, want=%v)", m.String(), s)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package annotation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/troublete/go-annotation/analyze"
+	"github.com/troublete/go-annotation/inspect"
+)
+
+// Generator generates files from the annotations of a result, through a writer shared between generators
+type Generator interface {
+	Generate(r *Result, w *Writer) error
+}
+
+// GeneratorFunc implements a generator with a function
+type GeneratorFunc func(r *Result, w *Writer) error
+
+func (f GeneratorFunc) Generate(r *Result, w *Writer) error {
+	return f(r, w)
+}
+
+// Event is passed to handlers for every annotation subscribed to, alongside the annotated spec
+type Event struct {
+	Match
+
+	// Annotation is the annotation subscribed to
+	Annotation analyze.Definition
+}
+
+// Decode decodes the attributes of the annotation into v (see analyze.Unmarshal)
+func (e Event) Decode(v any) error {
+	return analyze.Unmarshal(e.Annotation, v)
+}
+
+// HandlerFunc handles an annotation subscribed to
+type HandlerFunc func(w *Writer, e Event) error
+
+// Decoded returns a handler decoding the attributes of the annotation into T before passing them to fn
+func Decoded[T any](fn func(w *Writer, m Match, v T) error) HandlerFunc {
+	return func(w *Writer, e Event) error {
+		var v T
+		if err := e.Decode(&v); err != nil {
+			return err
+		}
+		return fn(w, e.Match, v)
+	}
+}
+
+type handler struct {
+	identifier string
+	target     analyze.Target
+	fn         HandlerFunc
+}
+
+// Generators is a registry of handlers subscribed to annotations by identifier (patterns like `crud.*` are supported)
+// and target; it is a generator itself, calling the handlers in order of subscription for every annotation matched
+type Generators struct {
+	handlers []handler
+}
+
+func (g *Generators) on(target analyze.Target, identifier string, fn HandlerFunc) *Generators {
+	g.handlers = append(g.handlers, handler{identifier: identifier, target: target, fn: fn})
+	return g
+}
+
+// OnType subscribes to annotations of types
+func (g *Generators) OnType(identifier string, fn HandlerFunc) *Generators {
+	return g.on(analyze.TargetType, identifier, fn)
+}
+
+// OnField subscribes to annotations of struct fields
+func (g *Generators) OnField(identifier string, fn HandlerFunc) *Generators {
+	return g.on(analyze.TargetField, identifier, fn)
+}
+
+// OnFunc subscribes to annotations of functions
+func (g *Generators) OnFunc(identifier string, fn HandlerFunc) *Generators {
+	return g.on(analyze.TargetFunc, identifier, fn)
+}
+
+// OnMethod subscribes to annotations of methods
+func (g *Generators) OnMethod(identifier string, fn HandlerFunc) *Generators {
+	return g.on(analyze.TargetMethod, identifier, fn)
+}
+
+// Generate calls the handlers for all annotations subscribed to; all handlers are called, errors are joined and name
+// the spec they occurred on
+func (g *Generators) Generate(r *Result, w *Writer) error {
+	ix := NewIndex(r)
+
+	var errs []error
+	for _, h := range g.handlers {
+		for _, m := range ix.Find(Query{Identifier: h.identifier, Targets: []analyze.Target{h.target}}) {
+			for _, d := range m.Annotations {
+				if err := h.fn(w, Event{Match: m, Annotation: d}); err != nil {
+					errs = append(errs, fmt.Errorf("%v: %v: %w", m.Reference, d.Identifier, err))
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Generate runs all generators on the result with a shared writer and writes the files generated; the paths written
+// are returned
+func Generate(r *Result, header string, gs ...Generator) ([]string, error) {
+	w := &Writer{Header: header}
+
+	var errs []error
+	for _, g := range gs {
+		if err := g.Generate(r, w); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return w.WriteFiles()
+}
+
+// WatchGenerate reads the annotations of the packages of the watcher and runs the generators on the result, afterwards
+// whenever packages changed until the context is done; done is called with the outcome of every run (the paths written
+// or the error). Packages failing to inspect keep their last state, results failing the strictness configured are not
+// generated from
+func WatchGenerate(ctx context.Context, w *inspect.Watcher, opts Options, header string, done func(paths []string, err error), gs ...Generator) error {
+	return w.Watch(ctx, func(c inspect.Change) {
+		res, err := ReadWithOptions(c.Types, c.Functions, opts)
+		if err != nil {
+			done(nil, errors.Join(c.Err, err))
+			return
+		}
+
+		paths, err := Generate(res, header, gs...)
+		done(paths, errors.Join(c.Err, err))
+	})
+}
@@ -0,0 +1,169 @@
+package annotation
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/troublete/go-annotation/inspect"
+)
+
+func Test_Generators(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "crud_gen.go")
+
+	type model struct {
+		Name string
+	}
+
+	g := &Generators{}
+	g.OnType("crud.model", Decoded(func(w *Writer, m Match, v model) error {
+		f := w.File(path, "model")
+		f.Import("strings")
+		f.Printf("func %sTable() string { return strings.ToUpper(%q) }\n", m.Name, v.Name)
+		return nil
+	})).OnField("crud.*", func(w *Writer, e Event) error {
+		f := w.File(path, "model")
+		f.Import("strings")
+		f.ImportAs("str", "strconv")
+		f.Printf("var _ = strings.ToLower(%q) + str.Quote(%q)\n", e.Parent.Type.Name, e.Annotation.Identifier)
+		return nil
+	}).OnMethod("crud.hook", func(w *Writer, e Event) error {
+		if e.Package == "other" {
+			return errors.New("unsupported package")
+		}
+		return nil
+	})
+
+	err := g.Generate(testConstraintResult(t), &Writer{})
+	if err == nil || err.Error() != "other.User.Save: crud.hook: unsupported package" {
+		t.Errorf("expected error of handler, got %v", err)
+	}
+
+	paths, err := Generate(testConstraintResult(t), "// Code generated by test. DO NOT EDIT.", &Generators{handlers: g.handlers[:2]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 || paths[0] != path {
+		t.Fatalf("unexpected paths %v", paths)
+	}
+
+	c, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `// Code generated by test. DO NOT EDIT.
+
+package model
+
+import (
+	str "strconv"
+	"strings"
+)
+
+func UserTable() string    { return strings.ToUpper("users") }
+func AccountTable() string { return strings.ToUpper("accounts") }
+
+var _ = strings.ToLower("User") + str.Quote("crud.field")
+var _ = strings.ToLower("User") + str.Quote("crud.field")
+var _ = strings.ToLower("User") + str.Quote("crud.index")
+var _ = strings.ToLower("Account") + str.Quote("crud.field")
+var _ = strings.ToLower("Account") + str.Quote("crud.field")
+var _ = strings.ToLower("Session") + str.Quote("crud.field")
+`
+	if string(c) != want {
+		t.Errorf("unexpected output\n%v", string(c))
+	}
+}
+
+func Test_GeneratorDecodeError(t *testing.T) {
+	type model struct {
+		Name int
+	}
+
+	err := (&Generators{}).OnType("crud.model", Decoded(func(w *Writer, m Match, v model) error {
+		return nil
+	})).Generate(testConstraintResult(t), &Writer{})
+	if err == nil || !strings.Contains(err.Error(), "model.User: crud.model:") {
+		t.Errorf("expected decode error, got %v", err)
+	}
+}
+
+func Test_WriterInvalidSource(t *testing.T) {
+	dir := t.TempDir()
+	w := &Writer{}
+	w.File(filepath.Join(dir, "a.go"), "a").Printf("func a() {}\n")
+	w.File(filepath.Join(dir, "b.go"), "b").Printf("func {\n")
+
+	if paths, err := w.WriteFiles(); err == nil || paths != nil {
+		t.Errorf("expected error on invalid source (paths=%v)", paths)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.go")); !os.IsNotExist(err) {
+		t.Error("expected no file written")
+	}
+}
+
+func Test_WriterPackageMismatch(t *testing.T) {
+	dir := t.TempDir()
+	w := &Writer{}
+	w.File(filepath.Join(dir, "a.go"), "a").Printf("func a() {}\n")
+	w.File(filepath.Join(dir, "a.go"), "b").Printf("func b() {}\n")
+
+	if _, err := w.Files(); err == nil || !strings.Contains(err.Error(), "package b") {
+		t.Errorf("expected package mismatch to fail, got %v", err)
+	}
+}
+
+func Test_WriterCreatesDirectories(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gen", "model", "a.go")
+	w := &Writer{}
+	w.File(path, "model").Printf("func a() {}\n")
+
+	if _, err := w.WriteFiles(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Error(err)
+	}
+}
+
+func Test_WatchGenerate(t *testing.T) {
+	root, out := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "model.go"), []byte("package model\n\n// crud.model{name=users}\ntype User struct{}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	g := GeneratorFunc(func(r *Result, w *Writer) error {
+		for _, at := range r.Types {
+			w.File(filepath.Join(out, "model_gen.go"), "model").Printf("const %sTable = %q\n", at.Type.Name, at.Annotations[0].Arguments["name"])
+		}
+		return nil
+	})
+
+	var written []string
+	err := WatchGenerate(ctx, &inspect.Watcher{Root: root, Interval: time.Millisecond}, Options{}, "", func(paths []string, err error) {
+		if err != nil {
+			t.Error(err)
+		}
+		written = paths
+		cancel()
+	}, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(written) != 1 {
+		t.Fatalf("expected generated file, got %v", written)
+	}
+	if src, err := os.ReadFile(written[0]); err != nil || !strings.Contains(string(src), `UserTable = "users"`) {
+		t.Errorf("unexpected source %s (err=%v)", src, err)
+	}
+}
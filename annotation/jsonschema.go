@@ -0,0 +1,145 @@
+package annotation
+
+import (
+	"encoding"
+	"reflect"
+	"strings"
+
+	"github.com/troublete/go-annotation/analyze"
+)
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// ResultJSONSchema returns the JSON Schema of the JSON encoding of a result (i.e. the output of cmd/inspect); if a
+// registry is passed, the annotations are additionally validated against the registered annotation schemas
+func ResultJSONSchema(r *analyze.Registry) map[string]any {
+	defs := map[string]any{}
+	js := jsonSchemaOf(reflect.TypeOf(Result{}), defs)
+	js["$schema"] = analyze.JSONSchemaDraft
+	js["title"] = "result"
+
+	if r != nil && len(r.Schemas()) > 0 {
+		definitions := r.JSONSchema()
+		for id, s := range definitions["$defs"].(map[string]any) {
+			defs[id] = s
+		}
+
+		// unknown annotations are kept in the result by the registry (only diagnosed, unless allowed), so any
+		// definition not registered must stay valid as well
+		var identifiers []string
+		for _, s := range r.Schemas() {
+			identifiers = append(identifiers, s.Identifier)
+		}
+		unknown := map[string]any{
+			"allOf": []any{
+				map[string]any{"$ref": "#/$defs/Definition"},
+				map[string]any{"properties": map[string]any{
+					"identifier": map[string]any{"not": map[string]any{"enum": identifiers}},
+				}},
+			},
+		}
+		anyOf := append(definitions["oneOf"].([]any), unknown)
+		defs["DefinitionList"] = map[string]any{
+			"type":  []string{"array", "null"},
+			"items": map[string]any{"anyOf": anyOf},
+		}
+	}
+
+	js["$defs"] = defs
+	return js
+}
+
+// jsonSchemaOf returns the JSON Schema of the JSON encoding of a type; named structs and lists are put into defs and
+// referenced
+func jsonSchemaOf(t reflect.Type, defs map[string]any) map[string]any {
+	if t.Implements(textMarshalerType) {
+		return map[string]any{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return jsonSchemaOf(t.Elem(), defs)
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Map:
+		// nil maps and slices are encoded as null
+		return map[string]any{"type": []string{"object", "null"}, "additionalProperties": jsonSchemaOf(t.Elem(), defs)}
+	case reflect.Slice, reflect.Array:
+		list := func() map[string]any {
+			return map[string]any{"type": []string{"array", "null"}, "items": jsonSchemaOf(t.Elem(), defs)}
+		}
+		if t.Name() == "" {
+			return list()
+		}
+		return reference(t.Name(), list, defs)
+	case reflect.Struct:
+		return reference(t.Name(), func() map[string]any {
+			return structSchema(t, defs)
+		}, defs)
+	}
+
+	return map[string]any{}
+}
+
+// reference builds and registers the schema in defs by name, if not done yet, and returns a reference to it; the name
+// is reserved before building, so recursive types terminate
+func reference(name string, build func() map[string]any, defs map[string]any) map[string]any {
+	if _, ok := defs[name]; !ok {
+		defs[name] = map[string]any{}
+		defs[name] = build()
+	}
+	return map[string]any{"$ref": "#/$defs/" + name}
+}
+
+// structSchema returns the schema of a struct following the rules of encoding/json on field names, omitempty and
+// embedding
+func structSchema(t reflect.Type, defs map[string]any) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() && !f.Anonymous {
+				continue
+			}
+
+			tag := f.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+				walk(f.Type)
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+
+			properties[name] = jsonSchemaOf(f.Type, defs)
+			if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Pointer {
+				required = append(required, name)
+			}
+		}
+	}
+	walk(t)
+
+	s := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
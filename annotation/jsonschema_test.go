@@ -0,0 +1,193 @@
+package annotation
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/troublete/go-annotation/analyze"
+	"github.com/troublete/go-annotation/inspect"
+)
+
+// validate is a minimal JSON Schema validator covering the keywords used by the exported schemas
+func validate(root, s map[string]any, v any, path string) error {
+	if ref, ok := s["$ref"].(string); ok {
+		return validate(root, root["$defs"].(map[string]any)[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any), v, path)
+	}
+
+	if c, ok := s["const"]; ok && c != v {
+		return fmt.Errorf("%v: expected %v, got %v", path, c, v)
+	}
+	if enum, ok := s["enum"].([]string); ok && !slices.ContainsFunc(enum, func(e string) bool { return e == v }) {
+		return fmt.Errorf("%v: %v is not one of %v", path, v, enum)
+	}
+	if not, ok := s["not"].(map[string]any); ok && validate(root, not, v, path) == nil {
+		return fmt.Errorf("%v: %v must not match %v", path, v, not)
+	}
+	if all, ok := s["allOf"].([]any); ok {
+		for _, a := range all {
+			if err := validate(root, a.(map[string]any), v, path); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, key := range []string{"anyOf", "oneOf"} {
+		if alternatives, ok := s[key].([]any); ok {
+			matches := 0
+			for _, a := range alternatives {
+				if validate(root, a.(map[string]any), v, path) == nil {
+					matches++
+				}
+			}
+			if matches == 0 || (key == "oneOf" && matches > 1) {
+				return fmt.Errorf("%v: %v alternatives matched for %v", path, matches, key)
+			}
+		}
+	}
+
+	var types []string
+	switch t := s["type"].(type) {
+	case string:
+		types = []string{t}
+	case []string:
+		types = t
+	}
+	if len(types) > 0 && !slices.Contains(types, jsonType(v)) {
+		return fmt.Errorf("%v: expected %v, got %v", path, types, jsonType(v))
+	}
+
+	switch v := v.(type) {
+	case string:
+		if pattern, ok := s["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(v) {
+			return fmt.Errorf("%v: '%v' does not match %v", path, v, pattern)
+		}
+	case []any:
+		if items, ok := s["items"].(map[string]any); ok {
+			for idx, item := range v {
+				if err := validate(root, items, item, fmt.Sprintf("%v[%v]", path, idx)); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]any:
+		required, _ := s["required"].([]string)
+		for _, r := range required {
+			if _, ok := v[r]; !ok {
+				return fmt.Errorf("%v: missing required property %v", path, r)
+			}
+		}
+
+		properties, _ := s["properties"].(map[string]any)
+		for key, value := range v {
+			if p, ok := properties[key]; ok {
+				if err := validate(root, p.(map[string]any), value, path+"."+key); err != nil {
+					return err
+				}
+				continue
+			}
+
+			switch additional := s["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					return fmt.Errorf("%v: unexpected property %v", path, key)
+				}
+			case map[string]any:
+				if err := validate(root, additional, value, path+"."+key); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func jsonType(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	}
+	return "object"
+}
+
+func encoded(t *testing.T, r *Result) any {
+	c, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v any
+	if err := json.Unmarshal(c, &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func Test_ResultJSONSchema(t *testing.T) {
+	types, err := inspect.FindAllTypes("../example/complex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	funcs, err := inspect.FindAllFunctions("../example/complex")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("without registry", func(t *testing.T) {
+		r, err := ReadWithOptions(types, funcs, Options{Directives: true, Strictness: NeverFail})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		js := ResultJSONSchema(nil)
+		if err := validate(js, js, encoded(t, r), "$"); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("with registry", func(t *testing.T) {
+		registry, err := LoadSchemas("../example/complex", analyze.Analyzer{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := ReadWithOptions(types, funcs, Options{Registry: registry, Strictness: NeverFail})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		js := ResultJSONSchema(registry)
+		if err := validate(js, js, encoded(t, r), "$"); err != nil {
+			t.Error(err)
+		}
+
+		invalid := &Result{Types: []AnnotatedType{{
+			Annotations: analyze.DefinitionList{{Identifier: "crud.model", Arguments: map[string]string{}}},
+		}}}
+		if err := validate(js, js, encoded(t, invalid), "$"); err == nil {
+			t.Error("expected annotation without required attribute to be invalid")
+		}
+
+		unknown := &Result{Types: []AnnotatedType{{
+			Annotations: analyze.DefinitionList{{Identifier: "cache.ttl", Arguments: map[string]string{"value": "1m"}}},
+		}}}
+		if err := validate(js, js, encoded(t, unknown), "$"); err != nil {
+			t.Errorf("expected unregistered annotation to be valid, as the registry keeps it: %v", err)
+		}
+	})
+}
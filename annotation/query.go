@@ -0,0 +1,268 @@
+package annotation
+
+import (
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/troublete/go-annotation/analyze"
+)
+
+// Match is a spec of a result matched by a query; depending on the target either Type, Field or Function is set
+type Match struct {
+	Target    analyze.Target `json:"target"`
+	Reference string         `json:"reference"`
+	Package   string         `json:"package"`
+	Name      string         `json:"name"`
+
+	Type     *AnnotatedType     `json:"type,omitempty"`
+	Field    *AnnotatedField    `json:"field,omitempty"`
+	Function *AnnotatedFunction `json:"function,omitempty"`
+
+	// Parent is the type a field or method belongs to (nil for types, functions and methods on types not part of the
+	// result)
+	Parent *AnnotatedType `json:"-"`
+
+	// Annotations are the annotations of the spec matched by the query; all annotations of the spec if the query
+	// doesn't select any
+	Annotations analyze.DefinitionList `json:"annotations,omitempty"`
+}
+
+// AllAnnotations returns all annotations of the matched spec, regardless of the query
+func (m Match) AllAnnotations() analyze.DefinitionList {
+	switch {
+	case m.Type != nil:
+		return m.Type.Annotations
+	case m.Field != nil:
+		return m.Field.Annotations
+	case m.Function != nil:
+		return m.Function.Annotations
+	}
+	return nil
+}
+
+// Position returns the position of the matched spec
+func (m Match) Position() analyze.Position {
+	switch {
+	case m.Type != nil:
+		return analyze.Position{File: m.Type.Type.FilePath, Line: m.Type.Type.Line}
+	case m.Field != nil:
+		return analyze.Position{File: m.Field.Field.FilePath, Line: m.Field.Field.Line}
+	case m.Function != nil:
+		return analyze.Position{File: m.Function.Function.FilePath, Line: m.Function.Function.Line}
+	}
+	return analyze.Position{}
+}
+
+// Predicate matches an annotation (e.g. by attribute)
+type Predicate func(d analyze.Definition) bool
+
+// Has matches annotations with the attribute set
+func Has(key string) Predicate {
+	return func(d analyze.Definition) bool {
+		_, ok := d.Arguments[key]
+		return ok
+	}
+}
+
+// Equals matches annotations with the attribute set to value
+func Equals(key, value string) Predicate {
+	return func(d analyze.Definition) bool {
+		v, ok := d.Arguments[key]
+		return ok && v == value
+	}
+}
+
+// MatchesAttribute matches annotations with the attribute value matching the regular expression
+func MatchesAttribute(key string, re *regexp.Regexp) Predicate {
+	return func(d analyze.Definition) bool {
+		v, ok := d.Arguments[key]
+		return ok && re.MatchString(v)
+	}
+}
+
+// Query selects specs of a result; all fields set must match. Identifier and Name are patterns as understood by
+// path.Match, so wildcard namespaces like `crud.*` are supported
+type Query struct {
+	// Identifier selects specs with at least one annotation matching the pattern
+	Identifier string
+	// Predicates select specs with at least one annotation (matching Identifier, if set) satisfying all predicates
+	Predicates []Predicate
+	// Package selects specs of the package
+	Package string
+	// Name selects specs with a matching name (type, field or function name)
+	Name string
+	// Targets select specs of any of the targets
+	Targets []analyze.Target
+}
+
+// selects returns if the query selects annotations, otherwise it only selects specs
+func (q Query) selects() bool {
+	return q.Identifier != "" || len(q.Predicates) > 0
+}
+
+// annotations returns the annotations of the list matching identifier and predicates
+func (q Query) annotations(dl analyze.DefinitionList) analyze.DefinitionList {
+	var matched analyze.DefinitionList
+	for _, d := range dl {
+		if q.Identifier != "" {
+			if ok, _ := path.Match(q.Identifier, d.Identifier); !ok {
+				continue
+			}
+		}
+
+		ok := true
+		for _, p := range q.Predicates {
+			ok = ok && p(d)
+		}
+		if ok {
+			matched = append(matched, d)
+		}
+	}
+	return matched
+}
+
+// Index indexes all specs of a result by annotation identifier and package for repeated queries; the result must not
+// be modified while the index is used
+type Index struct {
+	specs        []Match
+	byIdentifier map[string][]int
+	byPackage    map[string][]int
+}
+
+// NewIndex indexes the result
+func NewIndex(r *Result) *Index {
+	ix := &Index{
+		byIdentifier: map[string][]int{},
+		byPackage:    map[string][]int{},
+	}
+
+	add := func(m Match) {
+		idx := len(ix.specs)
+		ix.specs = append(ix.specs, m)
+		ix.byPackage[m.Package] = append(ix.byPackage[m.Package], idx)
+
+		seen := map[string]bool{}
+		for _, d := range m.AllAnnotations() {
+			if !seen[d.Identifier] {
+				seen[d.Identifier] = true
+				ix.byIdentifier[d.Identifier] = append(ix.byIdentifier[d.Identifier], idx)
+			}
+		}
+	}
+
+	for tidx := range r.Types {
+		at := &r.Types[tidx]
+		add(Match{
+			Target:    analyze.TargetType,
+			Reference: TypeReference(at.Type),
+			Package:   at.Type.Package,
+			Name:      at.Type.Name,
+			Type:      at,
+		})
+
+		for fidx := range at.Fields {
+			af := &at.Fields[fidx]
+			add(Match{
+				Target:    analyze.TargetField,
+				Reference: FieldReference(at.Type, af.Field),
+				Package:   at.Type.Package,
+				Name:      af.Field.Name,
+				Field:     af,
+				Parent:    at,
+			})
+		}
+	}
+
+	for fidx := range r.Functions {
+		af := &r.Functions[fidx]
+		add(Match{
+			Target:    FunctionTarget(af.Function),
+			Reference: FunctionReference(af.Function),
+			Package:   af.Function.Package,
+			Name:      af.Function.Name,
+			Function:  af,
+			Parent:    r.ReceiverType(af.Function),
+		})
+	}
+
+	return ix
+}
+
+// candidates returns the indices of the specs possibly matching the query, in order of the result
+func (ix *Index) candidates(q Query) []int {
+	var candidates []int
+	switch {
+	case q.Identifier != "" && !strings.ContainsAny(q.Identifier, `*?[\`):
+		candidates = ix.byIdentifier[q.Identifier]
+	case q.Identifier != "":
+		for identifier, indices := range ix.byIdentifier {
+			if ok, _ := path.Match(q.Identifier, identifier); ok {
+				candidates = append(candidates, indices...)
+			}
+		}
+		slices.Sort(candidates)
+		candidates = slices.Compact(candidates)
+	case q.Package != "":
+		candidates = ix.byPackage[q.Package]
+	default:
+		for idx := range ix.specs {
+			candidates = append(candidates, idx)
+		}
+	}
+	return candidates
+}
+
+// Find returns all specs matching the query, in order of the result (types followed by their fields, then functions)
+func (ix *Index) Find(q Query) []Match {
+	var matches []Match
+	for _, idx := range ix.candidates(q) {
+		m := ix.specs[idx]
+		if q.Package != "" && m.Package != q.Package {
+			continue
+		}
+		if len(q.Targets) > 0 && !slices.Contains(q.Targets, m.Target) {
+			continue
+		}
+		if q.Name != "" {
+			if ok, _ := path.Match(q.Name, m.Name); !ok {
+				continue
+			}
+		}
+
+		m.Annotations = m.AllAnnotations()
+		if q.selects() {
+			m.Annotations = q.annotations(m.Annotations)
+			if len(m.Annotations) == 0 {
+				continue
+			}
+		}
+		matches = append(matches, m)
+	}
+	return matches
+}
+
+// First returns the first spec matching the query, or nil if there is none
+func (ix *Index) First(q Query) *Match {
+	if matches := ix.Find(q); len(matches) > 0 {
+		return &matches[0]
+	}
+	return nil
+}
+
+// Find returns all specs of the result matching the query; use an index for repeated queries
+func (r *Result) Find(q Query) []Match {
+	return NewIndex(r).Find(q)
+}
+
+// Children returns the fields and methods of the type matching the query
+func (ix *Index) Children(parent *AnnotatedType, q Query) []Match {
+	var matches []Match
+	for _, m := range ix.Find(q) {
+		if m.Parent != nil && m.Parent == parent {
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
@@ -0,0 +1,125 @@
+package annotation
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/troublete/go-annotation/analyze"
+)
+
+func references(ms []Match) []string {
+	var refs []string
+	for _, m := range ms {
+		refs = append(refs, m.Reference)
+	}
+	return refs
+}
+
+func Test_IndexFind(t *testing.T) {
+	ix := NewIndex(testConstraintResult(t))
+
+	for _, tc := range []struct {
+		name  string
+		q     Query
+		specs []string
+	}{
+		{
+			name:  "identifier",
+			q:     Query{Identifier: "crud.model"},
+			specs: []string{"model.User", "model.Account"},
+		},
+		{
+			name:  "wildcard namespace",
+			q:     Query{Identifier: "crud.*", Targets: []analyze.Target{analyze.TargetMethod}},
+			specs: []string{"model.User.Save", "model.Session.Touch", "other.User.Save"},
+		},
+		{
+			name:  "attribute presence",
+			q:     Query{Identifier: "crud.field", Predicates: []Predicate{Has("primary")}},
+			specs: []string{"model.User.ID", "model.Account.ID", "model.Account.UserID"},
+		},
+		{
+			name:  "attribute value",
+			q:     Query{Predicates: []Predicate{Equals("name", "id")}},
+			specs: []string{"model.User.ID", "model.Account.ID"},
+		},
+		{
+			name:  "attribute pattern",
+			q:     Query{Identifier: "crud.field", Predicates: []Predicate{MatchesAttribute("name", regexp.MustCompile(`_id$`))}},
+			specs: []string{"model.Account.UserID"},
+		},
+		{
+			name:  "package",
+			q:     Query{Package: "other"},
+			specs: []string{"other.User.Save"},
+		},
+		{
+			name:  "name without annotation",
+			q:     Query{Name: "Sess*", Targets: []analyze.Target{analyze.TargetType}},
+			specs: []string{"model.Session"},
+		},
+		{
+			name: "no match",
+			q:    Query{Identifier: "chariot.*"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if refs := references(ix.Find(tc.q)); !slices.Equal(refs, tc.specs) {
+				t.Errorf("unexpected matches (has=%v, want=%v)", refs, tc.specs)
+			}
+		})
+	}
+}
+
+func Test_IndexMatch(t *testing.T) {
+	r := testConstraintResult(t)
+	ix := NewIndex(r)
+
+	m := ix.First(Query{Identifier: "crud.index"})
+	if m == nil || m.Target != analyze.TargetField || m.Field.Field.Name != "Name" || m.Parent != &r.Types[0] {
+		t.Fatalf("unexpected match %v", m)
+	}
+	if len(m.Annotations) != 1 || m.Annotations[0].Identifier != "crud.index" {
+		t.Errorf("expected only matched annotations, got %v", m.Annotations)
+	}
+
+	all := ix.First(Query{Name: "Name"})
+	if len(all.Annotations) != 2 {
+		t.Errorf("expected all annotations without selection, got %v", all.Annotations)
+	}
+
+	method := ix.First(Query{Identifier: "crud.hook", Package: "model", Name: "Touch"})
+	if method == nil || method.Function == nil || method.Parent != &r.Types[2] {
+		t.Errorf("expected method with parent type, got %v", method)
+	}
+
+	if m := ix.First(Query{Identifier: "crud.unknown"}); m != nil {
+		t.Errorf("expected no match, got %v", m)
+	}
+}
+
+func Test_IndexChildren(t *testing.T) {
+	r := testConstraintResult(t)
+	ix := NewIndex(r)
+
+	account := ix.First(Query{Identifier: "crud.model", Predicates: []Predicate{Equals("name", "accounts")}})
+	if account == nil {
+		t.Fatal("expected account")
+	}
+
+	children := ix.Children(account.Type, Query{Identifier: "crud.field", Predicates: []Predicate{Has("primary")}})
+	if refs := references(children); !slices.Equal(refs, []string{"model.Account.ID", "model.Account.UserID"}) {
+		t.Errorf("unexpected children %v", refs)
+	}
+
+	if refs := references(ix.Children(&r.Types[0], Query{Targets: []analyze.Target{analyze.TargetMethod}})); !slices.Equal(refs, []string{"model.User.Save"}) {
+		t.Errorf("unexpected methods %v", refs)
+	}
+}
+
+func Test_ResultFind(t *testing.T) {
+	if refs := references(testConstraintResult(t).Find(Query{Identifier: "crud.mod?l"})); len(refs) != 2 {
+		t.Errorf("unexpected matches %v", refs)
+	}
+}
@@ -2,56 +2,181 @@ package annotation
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/troublete/go-annotation/analyze"
 	"github.com/troublete/go-annotation/inspect"
 )
 
 type AnnotatedFunction struct {
-	Function    inspect.Function       `json:"function"`
-	Annotations analyze.DefinitionList `json:"annotations,omitempty"`
+	Function       inspect.Function       `json:"function"`
+	Annotations    analyze.DefinitionList `json:"annotations,omitempty"`
+	Directives     analyze.DirectiveList  `json:"directives,omitempty"`
+	FileDirectives analyze.DirectiveList  `json:"file_directives,omitempty"`
 }
 
 type AnnotatedType struct {
-	Type        inspect.Type           `json:"type"`
-	Annotations analyze.DefinitionList `json:"annotations,omitempty"`
-	Fields      []AnnotatedField       `json:"fields"`
+	Type           inspect.Type           `json:"type"`
+	Annotations    analyze.DefinitionList `json:"annotations,omitempty"`
+	Directives     analyze.DirectiveList  `json:"directives,omitempty"`
+	FileDirectives analyze.DirectiveList  `json:"file_directives,omitempty"`
+	Fields         []AnnotatedField       `json:"fields"`
 }
 
 type AnnotatedField struct {
 	Field       inspect.Field          `json:"field"`
 	Annotations analyze.DefinitionList `json:"annotations,omitempty"`
+	Directives  analyze.DirectiveList  `json:"directives,omitempty"`
 }
 
 type Result struct {
-	Functions []AnnotatedFunction `json:"functions,omitempty"`
-	Types     []AnnotatedType     `json:"types,omitempty"`
+	Functions   []AnnotatedFunction `json:"functions,omitempty"`
+	Types       []AnnotatedType     `json:"types,omitempty"`
+	Diagnostics analyze.Diagnostics `json:"diagnostics,omitempty"`
+}
+
+// TypeReference returns the reference of a type used in diagnostics (e.g. `complex.User`)
+func TypeReference(t inspect.Type) string {
+	return fmt.Sprintf("%s.%s", t.Package, t.Name)
+}
+
+// FieldReference returns the reference of a field used in diagnostics (e.g. `complex.User.ID`)
+func FieldReference(t inspect.Type, f inspect.Field) string {
+	return fmt.Sprintf("%s.%s", TypeReference(t), f.Name)
+}
+
+// FunctionReference returns the reference of a function used in diagnostics (e.g. `demo.AFunction` or
+// `demo.Receiver.Method`)
+func FunctionReference(f inspect.Function) string {
+	if f.Receiver != nil {
+		return fmt.Sprintf("%s.%s.%s", f.Package, f.Receiver.ReceiverType, f.Name)
+	}
+	return fmt.Sprintf("%s.%s", f.Package, f.Name)
+}
+
+// FunctionTarget returns the annotation target of a function, which is a method if it is defined on a receiver
+func FunctionTarget(f inspect.Function) analyze.Target {
+	if f.Receiver != nil {
+		return analyze.TargetMethod
+	}
+	return analyze.TargetFunc
+}
+
+// extract extracts the definitions of a spec and validates them against the registry, if set
+func extract(a analyze.Analyzer, r *analyze.Registry, target analyze.Target, s analyze.Spec) (analyze.DefinitionList, analyze.Diagnostics) {
+	defs, diagnostics := a.ExtractDefinitions(s)
+	if r == nil {
+		return defs, diagnostics
+	}
+
+	defs, validation := r.Validate(target, defs)
+	return defs, append(diagnostics, validation...)
 }
 
+// Strictness declares on which diagnostics reading annotations fails
+type Strictness int
+
+const (
+	// FailOnWarning fails if any warning or error is diagnosed (default)
+	FailOnWarning Strictness = iota
+	// FailOnError fails only if an error is diagnosed
+	FailOnError
+	// NeverFail never fails, the diagnostics are only collected
+	NeverFail
+)
+
+var strictnessNames = map[Strictness]string{
+	FailOnWarning: "warning",
+	FailOnError:   "error",
+	NeverFail:     "never",
+}
+
+func (s Strictness) String() string {
+	if n, ok := strictnessNames[s]; ok {
+		return n
+	}
+	return fmt.Sprintf("strictness(%d)", int(s))
+}
+
+// ParseStrictness returns the strictness by its name (warning, error or never)
+func ParseStrictness(name string) (Strictness, error) {
+	for s, n := range strictnessNames {
+		if n == name {
+			return s, nil
+		}
+	}
+	return FailOnWarning, fmt.Errorf("unknown strictness '%v'", name)
+}
+
+// Failing returns the diagnostics leading to a failure with the strictness
+func (s Strictness) Failing(ds analyze.Diagnostics) analyze.Diagnostics {
+	switch s {
+	case FailOnWarning:
+		return ds.AtLeast(analyze.SeverityWarning)
+	case FailOnError:
+		return ds.AtLeast(analyze.SeverityError)
+	}
+	return nil
+}
+
+// Options configure how annotations are read
+type Options struct {
+	// Analyzer is used to extract the definitions from the specs; if no filter is set, comments not matching the
+	// definition form are skipped
+	Analyzer analyze.Analyzer
+
+	// Directives enables the extraction of directives (e.g. `//go:generate ...`) alongside the annotations, including
+	// the directives of the file of types and functions (e.g. `//go:build linux`)
+	Directives bool
+
+	// Strictness declares on which diagnostics reading fails
+	Strictness Strictness
+
+	// Registry, if set, is used to validate the annotations against their schemas and to apply defaults
+	Registry *analyze.Registry
+
+	// Constraints are checked on the result after all annotations are read
+	Constraints []Constraint
+}
+
+// Read extracts the annotations of all types, fields and functions passed using the default options
 func Read(types inspect.TypeList, funcs inspect.FunctionList) (*Result, error) {
+	return ReadWithOptions(types, funcs, Options{})
+}
+
+// ReadWithOptions extracts the annotations of all types, fields and functions passed; all diagnostics are collected
+// on the result, alongside the valid annotations. If diagnostics fail the configured strictness, no result is
+// returned but the failing diagnostics joined as error
+func ReadWithOptions(types inspect.TypeList, funcs inspect.FunctionList, opts Options) (*Result, error) {
+	a := opts.Analyzer
+	if a.Filter == nil {
+		a.Filter = analyze.FilterCommentNoAnnotation()
+	}
+
 	result := Result{}
 	for _, t := range types {
 		at := AnnotatedType{
 			Type: t,
 		}
 
-		defs, warnings := analyze.ExtractDefinitionsOnSpec(t, analyze.FilterCommentNoAnnotation())
-		if len(warnings) > 0 {
-			return nil, fmt.Errorf("warnings occured: %v", strings.Join(warnings, ", "))
-		}
+		defs, diagnostics := extract(a, opts.Registry, analyze.TargetType, t)
+		result.Diagnostics = append(result.Diagnostics, diagnostics.WithSpec(TypeReference(t))...)
 		at.Annotations = defs
+		if opts.Directives {
+			at.Directives = analyze.ExtractDirectivesOnSpec(t)
+			at.FileDirectives = analyze.ExtractFileDirectivesOnSpec(t)
+		}
 
 		for _, f := range t.Fields {
 			af := AnnotatedField{
 				Field: f,
 			}
 
-			defs, warnings := analyze.ExtractDefinitionsOnSpec(f, analyze.FilterCommentNoAnnotation())
-			if len(warnings) > 0 {
-				return nil, fmt.Errorf("warnings occured: %v", strings.Join(warnings, ", "))
-			}
+			defs, diagnostics := extract(a, opts.Registry, analyze.TargetField, f)
+			result.Diagnostics = append(result.Diagnostics, diagnostics.WithSpec(FieldReference(t, f))...)
 			af.Annotations = defs
+			if opts.Directives {
+				af.Directives = analyze.ExtractDirectivesOnSpec(f)
+			}
 			at.Fields = append(at.Fields, af)
 		}
 
@@ -62,14 +187,23 @@ func Read(types inspect.TypeList, funcs inspect.FunctionList) (*Result, error) {
 		af := AnnotatedFunction{
 			Function: f,
 		}
-		defs, warnings := analyze.ExtractDefinitionsOnSpec(f, analyze.FilterCommentNoAnnotation())
-		if len(warnings) > 0 {
-			return nil, fmt.Errorf("warnings occured: %v", strings.Join(warnings, ", "))
-		}
+
+		defs, diagnostics := extract(a, opts.Registry, FunctionTarget(f), f)
+		result.Diagnostics = append(result.Diagnostics, diagnostics.WithSpec(FunctionReference(f))...)
 		af.Annotations = defs
+		if opts.Directives {
+			af.Directives = analyze.ExtractDirectivesOnSpec(f)
+			af.FileDirectives = analyze.ExtractFileDirectivesOnSpec(f)
+		}
 
 		result.Functions = append(result.Functions, af)
 	}
 
+	result.Diagnostics = append(result.Diagnostics, CheckConstraints(&result, opts.Constraints...)...)
+
+	if failing := opts.Strictness.Failing(result.Diagnostics); len(failing) > 0 {
+		return nil, failing.Err()
+	}
+
 	return &result, nil
 }
@@ -1,8 +1,10 @@
 package annotation
 
 import (
+	"errors"
 	"testing"
 
+	"github.com/troublete/go-annotation/analyze"
 	"github.com/troublete/go-annotation/inspect"
 )
 
@@ -109,3 +111,251 @@ func Test_Read(t *testing.T) {
 		}
 	})
 }
+
+func Test_ReadWithOptions(t *testing.T) {
+	t.Run("unique annotation repeated", func(t *testing.T) {
+		result, err := ReadWithOptions(inspect.TypeList{
+			inspect.Type{
+				Comments: []string{
+					`crud.model{name=users}`,
+					`crud.model{name=accounts}`,
+				},
+			},
+		}, nil, Options{
+			Analyzer: analyze.Analyzer{
+				Cardinalities: analyze.Cardinalities{
+					"crud.model": analyze.Unique,
+				},
+			},
+		})
+		if err == nil {
+			t.Error("expected error")
+		}
+		if result != nil {
+			t.Error("didn't expect results")
+		}
+	})
+
+	t.Run("default filter", func(t *testing.T) {
+		result, err := ReadWithOptions(inspect.TypeList{
+			inspect.Type{
+				Comments: []string{
+					`User is a model`,
+					`crud.model{name=users}`,
+				},
+			},
+		}, nil, Options{})
+		if err != nil {
+			t.Error(err)
+		}
+		if result == nil || len(result.Types[0].Annotations) != 1 {
+			t.Error("expected one annotation")
+		}
+	})
+}
+
+func Test_ReadDirectives(t *testing.T) {
+	types := inspect.TypeList{
+		inspect.Type{
+			Directives:     []string{"go:generate stringer -type=Kind"},
+			FileDirectives: []string{"go:build linux", "+build linux"},
+			Fields: []inspect.Field{
+				{
+					Directives: []string{"nolint"},
+				},
+			},
+		},
+	}
+	funcs := inspect.FunctionList{
+		{
+			Directives: []string{"go:noinline"},
+		},
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		result, err := Read(types, funcs)
+		if err != nil {
+			t.Error(err)
+		}
+		if result.Types[0].Directives != nil || result.Types[0].Fields[0].Directives != nil || result.Functions[0].Directives != nil {
+			t.Error("didn't expect directives")
+		}
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		result, err := ReadWithOptions(types, funcs, Options{Directives: true})
+		if err != nil {
+			t.Error(err)
+		}
+		if len(result.Types[0].Directives.Find("go", "generate")) != 1 ||
+			result.Types[0].Fields[0].Directives[0].Name != "nolint" ||
+			result.Functions[0].Directives[0].Name != "noinline" {
+			t.Error("expected directives")
+		}
+		if fd := result.Types[0].FileDirectives; len(fd.Find("go", "build")) != 1 || fd[1].Name != "+build" || fd[1].Arguments != "linux" {
+			t.Errorf("expected file directives, got %v", fd)
+		}
+	})
+}
+
+func Test_ReadDiagnostics(t *testing.T) {
+	_, err := Read(nil, inspect.FunctionList{
+		{
+			Comments:     []string{`chariot.route{method=GET,method=POST}`},
+			CommentLines: []int{3},
+			FilePath:     "routes.go",
+			Name:         "Handle",
+			Package:      "api",
+			Receiver:     &inspect.Receiver{ReceiverType: "Server"},
+		},
+	})
+
+	var d analyze.Diagnostic
+	if !errors.As(err, &d) {
+		t.Fatal("expected diagnostic error")
+	}
+
+	if d.Code != analyze.CodeAttributeDuplicateKey ||
+		d.Spec != "api.Server.Handle" ||
+		d.Position != (analyze.Position{File: "routes.go", Line: 3}) {
+		t.Errorf("unexpected diagnostic %v", d)
+	}
+}
+
+func Test_References(t *testing.T) {
+	ty := inspect.Type{Package: "complex", Name: "User"}
+	if TypeReference(ty) != "complex.User" ||
+		FieldReference(ty, inspect.Field{Name: "ID"}) != "complex.User.ID" ||
+		FunctionReference(inspect.Function{Package: "demo", Name: "AFunction"}) != "demo.AFunction" {
+		t.Error("unexpected reference")
+	}
+}
+
+func Test_ReadStrictness(t *testing.T) {
+	types := inspect.TypeList{
+		inspect.Type{
+			Comments: []string{
+				`crud.model{name=users}`,
+				`crud.model{name=`, // warning
+			},
+			Fields: []inspect.Field{
+				{
+					Comments: []string{
+						`crud.field{name=id}`,
+					},
+				},
+			},
+		},
+	}
+	funcs := inspect.FunctionList{
+		{
+			Comments: []string{
+				`chariot.route{method=GET}`,
+			},
+		},
+	}
+	erroneous := append(inspect.FunctionList{
+		{
+			Comments: []string{
+				`chariot.route{method=GET,method=POST}`, // error
+			},
+		},
+	}, funcs...)
+
+	for _, tc := range []struct {
+		name       string
+		strictness Strictness
+		funcs      inspect.FunctionList
+		fail       bool
+		n          int
+	}{
+		{"fail on warning", FailOnWarning, funcs, true, 0},
+		{"fail on error with warning", FailOnError, funcs, false, 1},
+		{"fail on error with error", FailOnError, erroneous, true, 0},
+		{"never fail", NeverFail, erroneous, false, 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := ReadWithOptions(types, tc.funcs, Options{Strictness: tc.strictness})
+			if tc.fail {
+				if err == nil || result != nil {
+					t.Error("expected failure")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(result.Diagnostics) != tc.n {
+				t.Errorf("expected %v diagnostics, got %v", tc.n, len(result.Diagnostics))
+			}
+
+			if len(result.Types[0].Annotations) != 1 ||
+				len(result.Types[0].Fields[0].Annotations) != 1 ||
+				len(result.Functions[len(result.Functions)-1].Annotations) != 1 {
+				t.Error("expected valid annotations to be returned")
+			}
+		})
+	}
+}
+
+func Test_ParseStrictness(t *testing.T) {
+	for _, s := range []Strictness{FailOnWarning, FailOnError, NeverFail} {
+		if p, err := ParseStrictness(s.String()); err != nil || p != s {
+			t.Errorf("failed to parse '%v'", s)
+		}
+	}
+
+	if _, err := ParseStrictness("sometimes"); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func Test_ReadRegistry(t *testing.T) {
+	r := &analyze.Registry{}
+	for _, s := range []analyze.Schema{
+		{Identifier: "crud.model", Targets: []analyze.Target{analyze.TargetType}},
+		{
+			Identifier: "crud.field",
+			Targets:    []analyze.Target{analyze.TargetField},
+			Attributes: []analyze.AttributeSchema{{Name: "name", Default: "unnamed"}},
+		},
+		{Identifier: "chariot.route", Targets: []analyze.Target{analyze.TargetMethod}},
+	} {
+		if err := r.Register(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := ReadWithOptions(inspect.TypeList{
+		inspect.Type{
+			Comments: []string{`crud.model{}`, `crud.field{}`},
+			Fields: []inspect.Field{
+				{Comments: []string{`crud.field{}`}},
+			},
+		},
+	}, inspect.FunctionList{
+		{Comments: []string{`chariot.route{}`}},
+		{Comments: []string{`chariot.route{}`}, Receiver: &inspect.Receiver{ReceiverType: "Server"}},
+	}, Options{
+		Registry:   r,
+		Strictness: NeverFail,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Diagnostics) != 2 ||
+		result.Diagnostics[0].Code != analyze.CodeTargetNotAllowed ||
+		result.Diagnostics[1].Code != analyze.CodeTargetNotAllowed {
+		t.Errorf("unexpected diagnostics %v", result.Diagnostics)
+	}
+
+	if len(result.Types[0].Annotations) != 1 ||
+		result.Types[0].Fields[0].Annotations[0].Arguments["name"] != "unnamed" ||
+		len(result.Functions[0].Annotations) != 0 ||
+		len(result.Functions[1].Annotations) != 1 {
+		t.Error("unexpected annotations")
+	}
+}
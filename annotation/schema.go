@@ -0,0 +1,271 @@
+package annotation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/troublete/go-annotation/analyze"
+	"github.com/troublete/go-annotation/inspect"
+)
+
+const (
+	// SchemaIdentifier marks a struct type as annotation schema, e.g.
+	// `annotation.schema{name="crud.field",targets="field"}`
+	SchemaIdentifier = "annotation.schema"
+	// AttrIdentifier describes a field of a schema struct type, e.g. `annotation.attr{required}`
+	AttrIdentifier = "annotation.attr"
+	// MetaNamespace is the namespace of the annotations used to declare schemas
+	MetaNamespace = "annotation"
+)
+
+// MetaSchemas are the schemas of the annotations used to declare schemas
+var MetaSchemas = []analyze.Schema{
+	{
+		Identifier: SchemaIdentifier,
+		Doc:        "declares the annotated struct type as annotation schema",
+		Targets:    []analyze.Target{analyze.TargetType},
+		Attributes: []analyze.AttributeSchema{
+			{Name: "name", Required: true, Doc: "identifier of the annotation"},
+			{Name: "targets", Doc: "comma separated targets the annotation is allowed on (type, field, func, method)"},
+			{Name: "repeatable", Type: analyze.ValueBool, Doc: "allows the annotation multiple times on a spec"},
+			{Name: "additional_attributes", Type: analyze.ValueBool, Doc: "allows attributes not declared"},
+			{Name: "doc", Doc: "documentation of the annotation"},
+		},
+	},
+	{
+		Identifier: AttrIdentifier,
+		Doc:        "describes an attribute of an annotation schema",
+		Targets:    []analyze.Target{analyze.TargetField},
+		Attributes: []analyze.AttributeSchema{
+			{Name: "name", Doc: "key of the attribute, defaults to the snake cased field name"},
+			{Name: "required", Type: analyze.ValueBool, Doc: "marks the attribute as required"},
+			{Name: "default", Doc: "default value of the attribute"},
+			{Name: "type", Doc: "value type of the attribute, defaults to the type derived from the field type"},
+			{Name: "doc", Doc: "documentation of the attribute"},
+		},
+	},
+}
+
+// ValueTypeOf derives the attribute value type from the type of a struct field; types not convertible are considered
+// strings
+func ValueTypeOf(ft inspect.FieldType) analyze.ValueType {
+	if ft.Package == "time" && ft.Name == "Duration" {
+		return analyze.ValueDuration
+	}
+
+	switch ft.Name {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		return analyze.ValueInt
+	case "float32", "float64":
+		return analyze.ValueFloat
+	case "bool":
+		return analyze.ValueBool
+	}
+	return analyze.ValueString
+}
+
+// SchemaFromType reads the schema of an annotated struct type; it returns false if the type is not annotated as
+// schema
+func SchemaFromType(at AnnotatedType) (analyze.Schema, bool, error) {
+	var def *analyze.Definition
+	for _, d := range at.Annotations {
+		if d.Identifier == SchemaIdentifier {
+			def = &d
+			break
+		}
+	}
+	if def == nil {
+		return analyze.Schema{}, false, nil
+	}
+
+	s := analyze.Schema{
+		Identifier: def.Arguments["name"],
+		Doc:        def.Arguments["doc"],
+		Position:   analyze.Position{File: at.Type.FilePath, Line: at.Type.Line},
+	}
+	if s.Identifier == "" {
+		return s, true, fmt.Errorf("schema of type '%v' has no name", TypeReference(at.Type))
+	}
+
+	if s.Doc == "" {
+		s.Doc = prose(at.Type.Comments)
+	}
+
+	if targets := def.Arguments["targets"]; targets != "" {
+		for _, t := range strings.Split(targets, ",") {
+			target := analyze.Target(strings.TrimSpace(t))
+			if !knownTarget(target) {
+				return s, true, fmt.Errorf("schema '%v' has unknown target '%v'", s.Identifier, target)
+			}
+			s.Targets = append(s.Targets, target)
+		}
+	}
+
+	var err error
+	if s.Repeatable, err = boolArgument(*def, "repeatable"); err != nil {
+		return s, true, fmt.Errorf("schema '%v': %w", s.Identifier, err)
+	}
+	if s.AdditionalAttributes, err = boolArgument(*def, "additional_attributes"); err != nil {
+		return s, true, fmt.Errorf("schema '%v': %w", s.Identifier, err)
+	}
+
+	for _, f := range at.Fields {
+		a := analyze.AttributeSchema{
+			Name: analyze.AttributeName(f.Field.Name),
+			Type: ValueTypeOf(f.Field.Type),
+			Doc:  prose(f.Field.Comments),
+		}
+
+		for _, d := range f.Annotations {
+			if d.Identifier != AttrIdentifier {
+				continue
+			}
+
+			if name := d.Arguments["name"]; name != "" {
+				a.Name = name
+			}
+			if vt := d.Arguments["type"]; vt != "" {
+				a.Type = analyze.ValueType(vt)
+				if !knownValueType(a.Type) {
+					return s, true, fmt.Errorf("attribute '%v' of schema '%v' has unknown type '%v'", a.Name, s.Identifier, vt)
+				}
+			}
+			if doc := d.Arguments["doc"]; doc != "" {
+				a.Doc = doc
+			}
+			a.Default = d.Arguments["default"]
+			if a.Required, err = boolArgument(d, "required"); err != nil {
+				return s, true, fmt.Errorf("attribute '%v' of schema '%v': %w", a.Name, s.Identifier, err)
+			}
+		}
+
+		s.Attributes = append(s.Attributes, a)
+	}
+
+	return s, true, nil
+}
+
+// RegisterSchemas registers the schemas of all struct types annotated as schema in the result, alongside the meta
+// schemas used to declare them (if not yet registered)
+func RegisterSchemas(r *analyze.Registry, res *Result) error {
+	for _, ms := range MetaSchemas {
+		if _, ok := r.Lookup(ms.Identifier); ok {
+			continue
+		}
+		if err := r.Register(ms); err != nil {
+			return err
+		}
+	}
+
+	for _, at := range res.Types {
+		s, ok, err := SchemaFromType(at)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		if err := r.Register(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SchemaAnalyzer returns the analyzer schema declarations are read with: the analyzer passed (e.g. with the marker and
+// grammar of the project), with the meta namespace allowed if the namespaces are restricted
+func SchemaAnalyzer(a analyze.Analyzer) analyze.Analyzer {
+	if len(a.Namespaces) > 0 {
+		a.Namespaces = append([]string{MetaNamespace}, a.Namespaces...)
+	}
+	return a
+}
+
+// LoadSchemas inspects the file tree starting at root and returns a registry containing all schemas declared; the
+// declarations are read with the SchemaAnalyzer of the analyzer passed
+func LoadSchemas(root string, a analyze.Analyzer) (*analyze.Registry, error) {
+	types, err := inspect.FindAllTypes(root)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := ReadWithOptions(types, nil, Options{Analyzer: SchemaAnalyzer(a), Strictness: FailOnError})
+	if err != nil {
+		return nil, err
+	}
+
+	r := &analyze.Registry{}
+	if err := RegisterSchemas(r, res); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ExtendSchemas returns a new registry with the schemas declared in the result and the schemas of base (may be nil),
+// e.g. to validate a package against its own schemas; declarations of the result take precedence over base schemas
+// with the same identifier, as they reflect the latest state of the source
+func ExtendSchemas(base *analyze.Registry, res *Result) (*analyze.Registry, error) {
+	r := &analyze.Registry{}
+	if err := RegisterSchemas(r, res); err != nil {
+		return nil, err
+	}
+
+	if base == nil {
+		return r, nil
+	}
+
+	r.AllowUnknown = base.AllowUnknown
+	for _, s := range base.Schemas() {
+		if _, ok := r.Lookup(s.Identifier); ok {
+			continue
+		}
+		if err := r.Register(s); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// prose returns the comment lines which are no annotations, joined by space
+func prose(comments []string) string {
+	var lines []string
+	for _, c := range comments {
+		if !analyze.GrammarV1.Definition.MatchString(c) && !analyze.GrammarV2.Definition.MatchString(c) {
+			lines = append(lines, c)
+		}
+	}
+	return strings.Join(lines, " ")
+}
+
+// boolArgument returns the boolean value of an attribute, false if not set
+func boolArgument(def analyze.Definition, key string) (bool, error) {
+	v, ok := def.Arguments[key]
+	if !ok {
+		return false, nil
+	}
+
+	b, err := analyze.ValueBool.Parse(v)
+	if err != nil {
+		return false, fmt.Errorf("attribute '%v' is not a bool: %w", key, err)
+	}
+	return b.(bool), nil
+}
+
+func knownTarget(t analyze.Target) bool {
+	for _, known := range analyze.Targets {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+func knownValueType(vt analyze.ValueType) bool {
+	for _, known := range analyze.ValueTypes {
+		if vt == known {
+			return true
+		}
+	}
+	return false
+}
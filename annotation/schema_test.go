@@ -0,0 +1,183 @@
+package annotation
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/troublete/go-annotation/analyze"
+	"github.com/troublete/go-annotation/inspect"
+)
+
+func Test_SchemaFromType(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		s, ok, err := SchemaFromType(AnnotatedType{
+			Type: inspect.Type{
+				Comments: []string{"Field marks a column", `annotation.schema{name="crud.field", targets="field,type", repeatable}`},
+				FilePath: "schema.go",
+				Line:     4,
+			},
+			Annotations: analyze.DefinitionList{
+				{Identifier: SchemaIdentifier, Arguments: map[string]string{"name": "crud.field", "targets": "field,type", "repeatable": analyze.TrueString}},
+			},
+			Fields: []AnnotatedField{
+				{
+					Field:       inspect.Field{Name: "Name", Type: inspect.FieldType{Name: "string"}, Comments: []string{"Name of the column"}},
+					Annotations: analyze.DefinitionList{{Identifier: AttrIdentifier, Arguments: map[string]string{"required": analyze.TrueString}}},
+				},
+				{
+					Field:       inspect.Field{Name: "MaxLength", Type: inspect.FieldType{Name: "int", Pointer: true}},
+					Annotations: analyze.DefinitionList{{Identifier: AttrIdentifier, Arguments: map[string]string{"default": "255"}}},
+				},
+				{
+					Field:       inspect.Field{Name: "Timeout", Type: inspect.FieldType{Package: "time", Name: "Duration"}},
+					Annotations: analyze.DefinitionList{{Identifier: AttrIdentifier, Arguments: map[string]string{"name": "ttl"}}},
+				},
+				{
+					Field: inspect.Field{Name: "Nullable", Type: inspect.FieldType{Name: "bool"}},
+				},
+			},
+		})
+		if err != nil || !ok {
+			t.Fatalf("expected schema (ok=%v, err=%v)", ok, err)
+		}
+
+		if s.Identifier != "crud.field" ||
+			s.Doc != "Field marks a column" ||
+			len(s.Targets) != 2 || s.Targets[0] != analyze.TargetField || s.Targets[1] != analyze.TargetType ||
+			!s.Repeatable ||
+			s.AdditionalAttributes ||
+			s.Position != (analyze.Position{File: "schema.go", Line: 4}) {
+			t.Errorf("unexpected schema %v", s)
+		}
+
+		for idx, want := range []analyze.AttributeSchema{
+			{Name: "name", Type: analyze.ValueString, Required: true, Doc: "Name of the column"},
+			{Name: "max_length", Type: analyze.ValueInt, Default: "255"},
+			{Name: "ttl", Type: analyze.ValueDuration},
+			{Name: "nullable", Type: analyze.ValueBool},
+		} {
+			if s.Attributes[idx] != want {
+				t.Errorf("attribute didn't match (has=%v, want=%v)", s.Attributes[idx], want)
+			}
+		}
+	})
+
+	t.Run("not a schema", func(t *testing.T) {
+		_, ok, err := SchemaFromType(AnnotatedType{})
+		if ok || err != nil {
+			t.Error("expected no schema")
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		for _, args := range []map[string]string{
+			{},
+			{"name": "a", "targets": "struct"},
+			{"name": "a", "repeatable": "maybe"},
+		} {
+			_, _, err := SchemaFromType(AnnotatedType{
+				Annotations: analyze.DefinitionList{{Identifier: SchemaIdentifier, Arguments: args}},
+			})
+			if err == nil {
+				t.Errorf("expected error for %v", args)
+			}
+		}
+	})
+}
+
+func Test_LoadSchemas(t *testing.T) {
+	r, err := LoadSchemas("../example/complex", analyze.Analyzer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{SchemaIdentifier, AttrIdentifier, "crud.model", "crud.field"} {
+		if _, ok := r.Lookup(id); !ok {
+			t.Errorf("expected '%v' to be registered", id)
+		}
+	}
+
+	types, err := inspect.FindAllTypes("../example/complex")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ReadWithOptions(types, nil, Options{Registry: r})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, at := range result.Types {
+		if at.Type.Name != "User" {
+			continue
+		}
+
+		if at.Fields[0].Annotations[0].Arguments["primary"] != "false" {
+			t.Error("expected default to be applied")
+		}
+	}
+}
+
+func Test_LoadSchemasAnalyzer(t *testing.T) {
+	root := t.TempDir()
+	src := "package demo\n\n// @annotation.schema{name=\"crud.model\"}\ntype Model struct {\n\t// @annotation.attr{required}\n\tName string\n}\n"
+	if err := os.WriteFile(filepath.Join(root, "schema.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := LoadSchemas(root, analyze.Analyzer{Marker: "@", Namespaces: []string{"crud"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, ok := r.Lookup("crud.model"); !ok || !s.Attributes[0].Required {
+		t.Error("expected schema declared with marker to be registered")
+	}
+
+	r, err = LoadSchemas(root, analyze.Analyzer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.Lookup("crud.model"); ok {
+		t.Error("expected schema declared with marker to be skipped without marker")
+	}
+}
+
+func Test_ExtendSchemas(t *testing.T) {
+	base, err := LoadSchemas("../example/complex", analyze.Analyzer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := &Result{Types: []AnnotatedType{
+		{
+			Type:        inspect.Type{Comments: []string{"Model redeclared"}},
+			Annotations: analyze.DefinitionList{{Identifier: SchemaIdentifier, Arguments: map[string]string{"name": "crud.model", "targets": "type"}}},
+		},
+		{
+			Annotations: analyze.DefinitionList{{Identifier: SchemaIdentifier, Arguments: map[string]string{"name": "crud.index"}}},
+		},
+	}}
+
+	r, err := ExtendSchemas(base, local)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"crud.model", "crud.field", "crud.index"} {
+		if _, ok := r.Lookup(id); !ok {
+			t.Errorf("expected '%v' to be registered", id)
+		}
+	}
+
+	if s, _ := r.Lookup("crud.model"); s.Doc != "Model redeclared" {
+		t.Error("expected local schema to take precedence")
+	}
+	if _, ok := base.Lookup("crud.index"); ok {
+		t.Error("expected base registry to be untouched")
+	}
+
+	if _, err := ExtendSchemas(nil, local); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,171 @@
+package annotation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/troublete/go-annotation/analyze"
+)
+
+// selectorKinds maps the kinds of a selector step to the targets selected
+var selectorKinds = map[string][]analyze.Target{
+	"*":                         nil,
+	string(analyze.TargetType):  {analyze.TargetType},
+	string(analyze.TargetField): {analyze.TargetField},
+	string(analyze.TargetFunc):  {analyze.TargetFunc},
+	"method":                    {analyze.TargetMethod},
+}
+
+// Selector is a chain of queries; each query after the first selects the fields and methods of the types matched
+// by its predecessor
+type Selector []Query
+
+// ParseSelector parses the selector language, a chain of steps combined with `>`, each step being a kind (`type`,
+// `field`, `func`, `method` or `*`) optionally followed by brackets holding an identifier pattern and attributes
+// (`key` for presence or `key=value`, values may be quoted), e.g. `type[crud.model] > field[crud.field name=id]` or
+// `func[chariot.route method=GET]`; use `*` as identifier to select by attributes only
+func ParseSelector(s string) (Selector, error) {
+	var sel Selector
+	for idx, step := range splitSteps(s) {
+		q, err := parseStep(strings.TrimSpace(step))
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector '%v': %w", s, err)
+		}
+
+		if idx > 0 {
+			previous := sel[idx-1].Targets
+			if len(previous) > 0 && previous[0] != analyze.TargetType {
+				return nil, fmt.Errorf("invalid selector '%v': only types have fields and methods", s)
+			}
+			if len(q.Targets) > 0 && q.Targets[0] != analyze.TargetField && q.Targets[0] != analyze.TargetMethod {
+				return nil, fmt.Errorf("invalid selector '%v': types only have fields and methods", s)
+			}
+		}
+		sel = append(sel, q)
+	}
+	return sel, nil
+}
+
+// parseStep parses a single step of a selector
+func parseStep(step string) (Query, error) {
+	kind, rest, bracketed := strings.Cut(step, "[")
+	kind = strings.TrimSpace(kind)
+	targets, ok := selectorKinds[kind]
+	if !ok {
+		return Query{}, fmt.Errorf("unknown kind '%v'", kind)
+	}
+
+	q := Query{Targets: targets}
+	if !bracketed {
+		return q, nil
+	}
+
+	if !strings.HasSuffix(rest, "]") {
+		return Query{}, fmt.Errorf("missing ']' in '%v'", step)
+	}
+
+	tokens, err := selectorTokens(strings.TrimSuffix(rest, "]"))
+	if err != nil {
+		return Query{}, err
+	}
+
+	for idx, token := range tokens {
+		key, value, assigned := strings.Cut(token, "=")
+		if idx == 0 && !assigned {
+			if key != "*" {
+				q.Identifier = key
+			}
+			continue
+		}
+
+		if key == "" {
+			return Query{}, fmt.Errorf("missing attribute key in '%v'", token)
+		}
+		if !assigned {
+			q.Predicates = append(q.Predicates, Has(key))
+			continue
+		}
+
+		if strings.HasPrefix(value, `"`) {
+			value, err = strconv.Unquote(value)
+			if err != nil {
+				return Query{}, fmt.Errorf("invalid quoted value in '%v': %w", token, err)
+			}
+		}
+		q.Predicates = append(q.Predicates, Equals(key, value))
+	}
+	return q, nil
+}
+
+// splitSteps splits a selector on `>` outside of quotes
+func splitSteps(s string) []string {
+	var steps []string
+	start, quoted, escaped := 0, false, false
+	for idx, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case !quoted && r == '>':
+			steps = append(steps, s[start:idx])
+			start = idx + 1
+		}
+	}
+	return append(steps, s[start:])
+}
+
+// selectorTokens splits the content of the brackets of a step on whitespace and commas outside of quotes
+func selectorTokens(s string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+	quoted, escaped := false, false
+	for _, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case !quoted && (unicode.IsSpace(r) || r == ','):
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+			continue
+		}
+		current.WriteRune(r)
+	}
+
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote in '%v'", s)
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+	return tokens, nil
+}
+
+// Select returns the matches of the last query of the selector
+func (s Selector) Select(ix *Index) []Match {
+	if len(s) == 0 {
+		return nil
+	}
+
+	matches := ix.Find(s[0])
+	for _, q := range s[1:] {
+		var children []Match
+		for _, m := range matches {
+			if m.Type != nil {
+				children = append(children, ix.Children(m.Type, q)...)
+			}
+		}
+		matches = children
+	}
+	return matches
+}
@@ -0,0 +1,73 @@
+package annotation
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/troublete/go-annotation/analyze"
+	"github.com/troublete/go-annotation/inspect"
+)
+
+func Test_ParseSelector(t *testing.T) {
+	for _, tc := range []struct {
+		selector string
+		valid    bool
+	}{
+		{selector: "type", valid: true},
+		{selector: "type[crud.model] > field[crud.field name=id]", valid: true},
+		{selector: `func[chariot.route method=GET, path="/users/{id}>"]`, valid: true},
+		{selector: "*[* primary]", valid: true},
+		{selector: "type > method[crud.*]", valid: true},
+		{selector: ""},
+		{selector: "struct[crud.model]"},
+		{selector: "type[crud.model"},
+		{selector: `type[crud.model name="users]`},
+		{selector: "type[crud.model =users]"},
+		{selector: "field > field"},
+		{selector: "type > func"},
+	} {
+		t.Run(tc.selector, func(t *testing.T) {
+			_, err := ParseSelector(tc.selector)
+			if (err == nil) != tc.valid {
+				t.Errorf("unexpected validity (err=%v)", err)
+			}
+		})
+	}
+}
+
+func Test_SelectorSelect(t *testing.T) {
+	r := testConstraintResult(t)
+	r.Functions = append(r.Functions, AnnotatedFunction{
+		Function:    inspect.Function{Name: "GetUser", Package: "api"},
+		Annotations: analyze.DefinitionList{{Identifier: "chariot.route", Arguments: map[string]string{"method": "GET", "path": "/users/{id}"}}},
+	}, AnnotatedFunction{
+		Function:    inspect.Function{Name: "PostUser", Package: "api"},
+		Annotations: analyze.DefinitionList{{Identifier: "chariot.route", Arguments: map[string]string{"method": "POST", "path": "/users"}}},
+	})
+	ix := NewIndex(r)
+
+	for _, tc := range []struct {
+		selector string
+		specs    []string
+	}{
+		{selector: "type[crud.model]", specs: []string{"model.User", "model.Account"}},
+		{selector: "type[crud.model] > field[crud.field name=id]", specs: []string{"model.User.ID", "model.Account.ID"}},
+		{selector: "type[crud.model name=accounts] > field[* primary]", specs: []string{"model.Account.ID", "model.Account.UserID"}},
+		{selector: "type > method", specs: []string{"model.User.Save", "model.Session.Touch"}},
+		{selector: "func[chariot.route method=GET]", specs: []string{"api.GetUser"}},
+		{selector: `func[chariot.* path="/users"]`, specs: []string{"api.PostUser"}},
+		{selector: "method[crud.hook]", specs: []string{"model.User.Save", "model.Session.Touch", "other.User.Save"}},
+		{selector: "type[crud.unknown] > field"},
+	} {
+		t.Run(tc.selector, func(t *testing.T) {
+			sel, err := ParseSelector(tc.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if refs := references(sel.Select(ix)); !slices.Equal(refs, tc.specs) {
+				t.Errorf("unexpected matches (has=%v, want=%v)", refs, tc.specs)
+			}
+		})
+	}
+}
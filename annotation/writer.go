@@ -0,0 +1,147 @@
+package annotation
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/troublete/go-annotation/internal/gofile"
+)
+
+// Writer collects generated Go files shared by generators; fragments written to the same file are concatenated and
+// their imports merged, the files are formatted like gofmt
+type Writer struct {
+	// Header is written on top of every file (e.g. `// Code generated by gen. DO NOT EDIT.`)
+	Header string
+
+	mu    sync.Mutex
+	files map[string]*File
+}
+
+// File is a generated Go file, assembled from fragments
+type File struct {
+	Path    string
+	Package string
+
+	mu       sync.Mutex
+	imports  []gofile.Import
+	body     bytes.Buffer
+	packages []string
+}
+
+// File returns the file at path with the package name, creating it on first use; fragments of all generators writing
+// to the same path end up in the same file, so the package name must match (otherwise Files fails)
+func (w *Writer) File(path, pkg string) *File {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.files == nil {
+		w.files = map[string]*File{}
+	}
+
+	path = filepath.Clean(path)
+	f, ok := w.files[path]
+	if !ok {
+		f = &File{Path: path, Package: pkg}
+		w.files[path] = f
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if pkg != f.Package && !slices.Contains(f.packages, pkg) {
+		f.packages = append(f.packages, pkg)
+	}
+	return f
+}
+
+// Import adds an import to the file
+func (f *File) Import(path string) {
+	f.ImportAs("", path)
+}
+
+// ImportAs adds an import with an alias to the file
+func (f *File) ImportAs(name, path string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.imports = append(f.imports, gofile.Import{Name: name, Path: path})
+}
+
+// Write appends a fragment to the body of the file
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.body.Write(p)
+}
+
+// Printf appends a formatted fragment to the body of the file
+func (f *File) Printf(format string, args ...any) {
+	fmt.Fprintf(f, format, args...)
+}
+
+// source returns the formatted source of the file
+func (f *File) source(header string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.packages) > 0 {
+		return nil, fmt.Errorf("file of package '%v' is written as package %v as well", f.Package, strings.Join(f.packages, ", "))
+	}
+
+	var src bytes.Buffer
+	if header != "" {
+		fmt.Fprintf(&src, "%s\n\n", header)
+	}
+	fmt.Fprintf(&src, "package %s\n\n", f.Package)
+	src.Write(f.body.Bytes())
+
+	return gofile.Format(f.Path, src.Bytes(), f.imports...)
+}
+
+// Files returns the formatted sources of all files by path
+func (w *Writer) Files() (map[string][]byte, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var errs []error
+	sources := map[string][]byte{}
+	for path, f := range w.files {
+		src, err := f.source(w.Header)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%v: %w", path, err))
+			continue
+		}
+		sources[path] = src
+	}
+	return sources, errors.Join(errs...)
+}
+
+// WriteFiles formats all files and writes them, creating missing directories; nothing is written if any file fails to
+// format. The paths written are returned, sorted
+func (w *Writer) WriteFiles() ([]string, error) {
+	sources, err := w.Files()
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for path := range sources {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, sources[path], 0o644); err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,205 @@
+// Package annotationcheck provides an analysis.Analyzer validating annotations, to run them in go vet, golangci-lint
+// or gopls
+package annotationcheck
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/troublete/go-annotation/analyze"
+	"github.com/troublete/go-annotation/annotation"
+	"github.com/troublete/go-annotation/inspect"
+)
+
+const Doc = `check annotations in comments
+
+The annotationcheck analyzer reports malformed annotations and, if schemas are configured or declared in the package,
+annotations violating their schema. Fixes are suggested for unbalanced curly brackets and unquoted values containing
+a separator.`
+
+// Config configures the analyzer; the fields are bound to the flags of the analyzer, so they can be set on the
+// command line as well
+type Config struct {
+	// Schemas is the root path to discover annotation schemas from
+	Schemas string
+	// Marker is required in front of annotations (e.g. @)
+	Marker string
+	// Namespaces is a comma separated list of allowed annotation namespaces
+	Namespaces string
+	// Grammar is the version of the annotation grammar
+	Grammar int
+}
+
+// Analyzer reports the diagnostics of the annotations of a package, configured by flags
+var Analyzer = NewAnalyzer(Config{Grammar: analyze.GrammarV1.Version})
+
+// NewAnalyzer returns an analyzer reporting the diagnostics of the annotations of a package with the configuration
+// passed, e.g. to run it with a fixed configuration in a multichecker
+func NewAnalyzer(c Config) *analysis.Analyzer {
+	ch := &checker{config: c}
+	a := &analysis.Analyzer{
+		Name: "annotation",
+		Doc:  Doc,
+		URL:  "https://github.com/troublete/go-annotation",
+		Run:  ch.run,
+	}
+	a.Flags.StringVar(&ch.config.Schemas, "schemas", c.Schemas, "root path to discover annotation schemas from")
+	a.Flags.StringVar(&ch.config.Marker, "marker", c.Marker, "marker required in front of annotations (e.g. @)")
+	a.Flags.StringVar(&ch.config.Namespaces, "namespaces", c.Namespaces, "comma separated list of allowed annotation namespaces")
+	a.Flags.IntVar(&ch.config.Grammar, "grammar", c.Grammar, "version of the annotation grammar (1 or 2)")
+	return a
+}
+
+// checker is the state of an analyzer; the schemas configured are loaded once for all packages analyzed
+type checker struct {
+	config Config
+
+	loadOnce   sync.Once
+	loaded     *analyze.Registry
+	loadingErr error
+}
+
+// loadSchemas loads the schemas configured once for all packages analyzed
+func (ch *checker) loadSchemas(a analyze.Analyzer) (*analyze.Registry, error) {
+	ch.loadOnce.Do(func() {
+		if ch.config.Schemas != "" {
+			ch.loaded, ch.loadingErr = annotation.LoadSchemas(ch.config.Schemas, a)
+		}
+	})
+	return ch.loaded, ch.loadingErr
+}
+
+// registry returns the registry the package is validated against: the schemas configured and the schemas declared in
+// the package; nil if there are none
+func (ch *checker) registry(a analyze.Analyzer, types inspect.TypeList) (*analyze.Registry, error) {
+	global, err := ch.loadSchemas(a)
+	if err != nil {
+		return nil, err
+	}
+
+	local, err := annotation.ReadWithOptions(types, nil, annotation.Options{
+		Analyzer:   annotation.SchemaAnalyzer(a),
+		Strictness: annotation.NeverFail,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := annotation.ExtendSchemas(global, local)
+	if err != nil {
+		return nil, err
+	}
+
+	// only the meta schemas are registered, so nothing is to be validated
+	if len(r.Schemas()) == len(annotation.MetaSchemas) {
+		return nil, nil
+	}
+	return r, nil
+}
+
+// analyzer returns the annotation analyzer configured; an unknown grammar version fails
+func (ch *checker) analyzer() (analyze.Analyzer, error) {
+	g, ok := analyze.Grammars[ch.config.Grammar]
+	if !ok {
+		return analyze.Analyzer{}, fmt.Errorf("unknown grammar version %v", ch.config.Grammar)
+	}
+
+	a := analyze.Analyzer{
+		Marker:  ch.config.Marker,
+		Grammar: g,
+		Filter:  analyze.FilterCommentNoAnnotation(),
+	}
+	if ch.config.Namespaces != "" {
+		a.Namespaces = strings.Split(ch.config.Namespaces, ",")
+	}
+	return a, nil
+}
+
+func (ch *checker) run(pass *analysis.Pass) (any, error) {
+	a, err := ch.analyzer()
+	if err != nil {
+		return nil, err
+	}
+
+	var types inspect.TypeList
+	var funcs inspect.FunctionList
+	comments := map[string]map[int]*ast.Comment{}
+	for _, f := range pass.Files {
+		types = append(types, inspect.FindFileTypes(pass.Fset, f)...)
+		funcs = append(funcs, inspect.FindFileFunctions(pass.Fset, f)...)
+
+		byLine := map[int]*ast.Comment{}
+		for _, cg := range f.Comments {
+			for _, c := range cg.List {
+				if line := pass.Fset.Position(c.Slash).Line; byLine[line] == nil {
+					byLine[line] = c
+				}
+			}
+		}
+		comments[pass.Fset.Position(f.Pos()).Filename] = byLine
+	}
+
+	r, err := ch.registry(a, types)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := annotation.ReadWithOptions(types, funcs, annotation.Options{
+		Analyzer:   a,
+		Registry:   r,
+		Strictness: annotation.NeverFail,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range res.Diagnostics {
+		c := comments[d.Position.File][d.Position.Line]
+		if c == nil {
+			continue // diagnostics without position, e.g. of constraints
+		}
+
+		diagnostic := analysis.Diagnostic{
+			Pos:      c.Slash,
+			End:      c.End(),
+			Category: d.Code,
+			Message:  d.Message,
+		}
+		if fix, ok := suggestedFix(a, c, d); ok {
+			diagnostic.SuggestedFixes = []analysis.SuggestedFix{fix}
+		}
+		pass.Report(diagnostic)
+	}
+	return nil, nil
+}
+
+// suggestedFix returns the fix of the diagnostic, replacing the text of the comment
+func suggestedFix(a analyze.Analyzer, c *ast.Comment, d analyze.Diagnostic) (analysis.SuggestedFix, bool) {
+	prefix, suffix := "//", ""
+	if strings.HasPrefix(c.Text, "/*") {
+		prefix, suffix = "/*", "*/"
+	}
+
+	body := strings.TrimSuffix(strings.TrimPrefix(c.Text, prefix), suffix)
+	text := strings.TrimSpace(body)
+	leading := body[:strings.Index(body, text)]
+	trailing := body[len(leading)+len(text):]
+
+	fixed, ok := a.Fix(text, d.Code)
+	if !ok {
+		return analysis.SuggestedFix{}, false
+	}
+
+	return analysis.SuggestedFix{
+		Message: d.SuggestedFix,
+		TextEdits: []analysis.TextEdit{{
+			Pos:     c.Slash,
+			End:     c.End(),
+			NewText: []byte(prefix + leading + fixed + trailing + suffix),
+		}},
+	}, true
+}
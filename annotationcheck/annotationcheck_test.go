@@ -0,0 +1,22 @@
+package annotationcheck
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func Test_Analyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), NewAnalyzer(Config{Grammar: 1}), "model")
+}
+
+func Test_AnalyzerFix(t *testing.T) {
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), NewAnalyzer(Config{Grammar: 1}), "fix")
+}
+
+func Test_AnalyzerUnknownGrammar(t *testing.T) {
+	ch := &checker{config: Config{Grammar: 3}}
+	if _, err := ch.analyzer(); err == nil {
+		t.Error("expected unknown grammar to fail")
+	}
+}
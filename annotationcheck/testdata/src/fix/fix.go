@@ -0,0 +1,10 @@
+package fix
+
+type User struct {
+	// want +1 "attribute format"
+	ID string /* chariot.route{path=/a,/b} */
+}
+
+// want +1 "bracket structure"
+// crud.model{name=users
+type Account struct{}
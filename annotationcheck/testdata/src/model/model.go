@@ -0,0 +1,30 @@
+package model
+
+// Model marks a persisted type
+// annotation.schema{name="crud.model",targets="type"}
+type Model struct {
+	// annotation.attr{required}
+	Name string
+}
+
+// Base is embedded into persisted types
+type Base struct {
+	Created string `json:"created,omitempty"`
+}
+
+// want +1 "bracket structure"
+// crud.model{name=users
+type User struct {
+	Base
+	*Model
+	// want +1 "not registered"
+	ID string `json:"x,omitempty" db:"id"` // crud.field{name=id}
+}
+
+// want +1 "is required"
+// crud.model{}
+type Account struct{}
+
+// want +1 "attribute format"
+// chariot.route{path=/a,/b}
+func Handle() {}
@@ -0,0 +1,155 @@
+package auxiliary
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/troublete/go-annotation/analyze"
+)
+
+// Range limits an integer attribute to [Min, Max]
+type Range struct {
+	Min int64
+	Max int64
+}
+
+// Rule describes how a single attribute is validated and coerced; all checks set are applied in order: required,
+// type, enum, pattern, range and custom
+type Rule struct {
+	// Required marks the attribute as required; optional attributes not set are skipped
+	Required bool
+	// Default is used if the attribute is not set; an empty default is considered no default
+	Default string
+	// Type the value is coerced into; strings if not set
+	Type analyze.ValueType
+	// Enum restricts the value to the listed values
+	Enum []string
+	// Pattern the value must match
+	Pattern *regexp.Regexp
+	// Range the value must be in, the value must be an integer
+	Range *Range
+	// Custom validation, returning the reason the value is invalid
+	Custom func(string) error
+}
+
+type Rules map[string]Rule
+
+// Violation names an attribute not passing its rule and why
+type Violation struct {
+	Attribute string
+	Value     string
+	Reason    string
+}
+
+func (v Violation) Error() string {
+	return fmt.Sprintf("attribute '%v': %v", v.Attribute, v.Reason)
+}
+
+// Report is the result of checking attributes against rules
+type Report struct {
+	// Attributes contains the raw values of all valid attributes, including defaults applied
+	Attributes Attributes
+	// Values contains the coerced values of all valid attributes (string, int64, float64, bool or time.Duration)
+	Values     map[string]any
+	Violations []Violation
+	Valid      bool
+}
+
+// Err returns all violations joined into a single error, or nil if there are none
+func (r Report) Err() error {
+	var errs []error
+	for _, v := range r.Violations {
+		errs = append(errs, v)
+	}
+	return errors.Join(errs...)
+}
+
+// Check validates the attributes against the rules and coerces them into typed values; attributes without rule are
+// filtered out, like with AttributeContract
+func Check(attrs Attributes, rules Rules) Report {
+	r := Report{
+		Attributes: Attributes{},
+		Values:     map[string]any{},
+	}
+
+	var names []string
+	for name := range rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		rule := rules[name]
+
+		v, ok := attrs[name]
+		if !ok {
+			if rule.Default != "" {
+				v = rule.Default
+			} else {
+				if rule.Required {
+					r.Violations = append(r.Violations, Violation{Attribute: name, Reason: "is required"})
+				}
+				continue
+			}
+		}
+
+		value, reason := rule.check(v)
+		if reason != "" {
+			r.Violations = append(r.Violations, Violation{Attribute: name, Value: v, Reason: reason})
+			continue
+		}
+
+		r.Attributes[name] = v
+		r.Values[name] = value
+	}
+
+	r.Valid = len(r.Violations) == 0
+	return r
+}
+
+// check validates and coerces a single value, returning the reason it is invalid
+func (rule Rule) check(v string) (any, string) {
+	value, err := rule.Type.Parse(v)
+	if err != nil {
+		return nil, fmt.Sprintf("is not of type %v", rule.Type)
+	}
+
+	if len(rule.Enum) > 0 {
+		found := false
+		for _, e := range rule.Enum {
+			if e == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Sprintf("must be one of %v", strings.Join(rule.Enum, ", "))
+		}
+	}
+
+	if rule.Pattern != nil && !rule.Pattern.MatchString(v) {
+		return nil, fmt.Sprintf("must match %v", rule.Pattern)
+	}
+
+	if rule.Range != nil {
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, "is not an integer"
+		}
+		if i < rule.Range.Min || i > rule.Range.Max {
+			return nil, fmt.Sprintf("must be between %v and %v", rule.Range.Min, rule.Range.Max)
+		}
+	}
+
+	if rule.Custom != nil {
+		if err := rule.Custom(v); err != nil {
+			return nil, err.Error()
+		}
+	}
+
+	return value, ""
+}
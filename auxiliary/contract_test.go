@@ -0,0 +1,94 @@
+package auxiliary
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/troublete/go-annotation/analyze"
+)
+
+func Test_Check(t *testing.T) {
+	rules := Rules{
+		"name": {Required: true, Pattern: regexp.MustCompile(`^[a-z_]+$`)},
+		"method": {
+			Default: "GET",
+			Enum:    []string{"GET", "POST"},
+		},
+		"limit":   {Type: analyze.ValueInt, Range: &Range{Min: 1, Max: 100}},
+		"timeout": {Type: analyze.ValueDuration, Default: "1s"},
+		"auth":    {Type: analyze.ValueBool},
+		"path": {Custom: func(v string) error {
+			if v == "" || v[0] != '/' {
+				return errors.New("must start with /")
+			}
+			return nil
+		}},
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		r := Check(Attributes{
+			"name":     "users",
+			"limit":    "10",
+			"auth":     analyze.TrueString,
+			"filtered": "out",
+		}, rules)
+
+		if !r.Valid || r.Err() != nil {
+			t.Fatalf("expected valid report, got %v", r.Violations)
+		}
+
+		if r.Values["name"] != "users" ||
+			r.Values["method"] != "GET" ||
+			r.Values["limit"] != int64(10) ||
+			r.Values["timeout"] != time.Second ||
+			r.Values["auth"] != true {
+			t.Errorf("unexpected values %v", r.Values)
+		}
+
+		if _, ok := r.Attributes["filtered"]; ok {
+			t.Error("expected attribute without rule to be filtered out")
+		}
+
+		if _, ok := r.Values["path"]; ok {
+			t.Error("didn't expect optional attribute not set")
+		}
+	})
+
+	t.Run("violations", func(t *testing.T) {
+		r := Check(Attributes{
+			"method":  "DELETE",
+			"limit":   "1000",
+			"timeout": "soon",
+			"path":    "users",
+		}, rules)
+
+		if r.Valid || r.Err() == nil {
+			t.Fatal("expected invalid report")
+		}
+
+		want := []Violation{
+			{Attribute: "limit", Value: "1000", Reason: "must be between 1 and 100"},
+			{Attribute: "method", Value: "DELETE", Reason: "must be one of GET, POST"},
+			{Attribute: "name", Reason: "is required"},
+			{Attribute: "path", Value: "users", Reason: "must start with /"},
+			{Attribute: "timeout", Value: "soon", Reason: "is not of type duration"},
+		}
+		if len(r.Violations) != len(want) {
+			t.Fatalf("expected %v violations, got %v", len(want), r.Violations)
+		}
+		for idx, v := range r.Violations {
+			if v != want[idx] {
+				t.Errorf("violation didn't match (has=%v, want=%v)", v, want[idx])
+			}
+		}
+	})
+
+	t.Run("pattern", func(t *testing.T) {
+		r := Check(Attributes{"name": "Users"}, rules)
+		if r.Valid || r.Violations[0].Attribute != "name" || r.Violations[0].Error() != "attribute 'name': must match ^[a-z_]+$" {
+			t.Errorf("unexpected violations %v", r.Violations)
+		}
+	})
+}
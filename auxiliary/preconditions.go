@@ -0,0 +1,103 @@
+package auxiliary
+
+import (
+	"go/token"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Precondition checks a single attribute value; usable with Preconditions
+type Precondition = func(string) bool
+
+// OneOf allows only the values listed
+func OneOf(values ...string) Precondition {
+	return func(v string) bool {
+		for _, a := range values {
+			if v == a {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Matches allows only values matching the expression
+func Matches(re *regexp.Regexp) Precondition {
+	return re.MatchString
+}
+
+// IsInt allows only integer values
+func IsInt(v string) bool {
+	_, err := strconv.ParseInt(v, 10, 64)
+	return err == nil
+}
+
+// IntBetween allows only integer values in [min, max]
+func IntBetween(min, max int64) Precondition {
+	return func(v string) bool {
+		i, err := strconv.ParseInt(v, 10, 64)
+		return err == nil && i >= min && i <= max
+	}
+}
+
+// IsBool allows only boolean values (see strconv.ParseBool), which includes TRUE of key-only attributes
+func IsBool(v string) bool {
+	_, err := strconv.ParseBool(v)
+	return err == nil
+}
+
+// IsDuration allows only durations (see time.ParseDuration)
+func IsDuration(v string) bool {
+	_, err := time.ParseDuration(v)
+	return err == nil
+}
+
+// IsURL allows only absolute URLs, with scheme and host
+func IsURL(v string) bool {
+	u, err := url.Parse(v)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+// IsIdentifier allows only valid Go identifiers (e.g. to reference types or functions)
+func IsIdentifier(v string) bool {
+	return token.IsIdentifier(v)
+}
+
+// NotEmpty allows only values not empty or whitespace
+func NotEmpty(v string) bool {
+	return strings.TrimSpace(v) != ""
+}
+
+// And allows values passing all preconditions
+func And(ps ...Precondition) Precondition {
+	return func(v string) bool {
+		for _, p := range ps {
+			if !p(v) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or allows values passing any of the preconditions
+func Or(ps ...Precondition) Precondition {
+	return func(v string) bool {
+		for _, p := range ps {
+			if p(v) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not allows values not passing the precondition
+func Not(p Precondition) Precondition {
+	return func(v string) bool {
+		return !p(v)
+	}
+}
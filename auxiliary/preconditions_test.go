@@ -0,0 +1,54 @@
+package auxiliary
+
+import (
+	"regexp"
+	"testing"
+)
+
+func Test_Preconditions(t *testing.T) {
+	for name, tc := range map[string]struct {
+		p     Precondition
+		allow []string
+		deny  []string
+	}{
+		"one of":        {OneOf("GET", "POST"), []string{"GET", "POST"}, []string{"get", "PUT", ""}},
+		"matches":       {Matches(regexp.MustCompile(`^/[a-z]*$`)), []string{"/", "/users"}, []string{"users", "/Users"}},
+		"is int":        {IsInt, []string{"1", "-20", "0"}, []string{"1.5", "one", ""}},
+		"int between":   {IntBetween(1, 10), []string{"1", "5", "10"}, []string{"0", "11", "five"}},
+		"is bool":       {IsBool, []string{"TRUE", "true", "false", "0"}, []string{"yes", ""}},
+		"is duration":   {IsDuration, []string{"1s", "1h30m"}, []string{"1", "soon"}},
+		"is url":        {IsURL, []string{"https://example.com/path", "postgres://db:5432"}, []string{"/path", "example.com", "http://"}},
+		"is identifier": {IsIdentifier, []string{"User", "_id", "größe"}, []string{"1user", "user-name", "func", ""}},
+		"not empty":     {NotEmpty, []string{"a", " a "}, []string{"", "  "}},
+		"and":           {And(NotEmpty, IsInt), []string{"1"}, []string{"", "a"}},
+		"or":            {Or(IsInt, IsBool), []string{"1", "TRUE"}, []string{"a"}},
+		"not":           {Not(IsInt), []string{"a"}, []string{"1"}},
+		"composed":      {Or(OneOf("none"), And(IsInt, Not(IntBetween(0, 9)))), []string{"none", "10", "-1"}, []string{"5", "x"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			for _, v := range tc.allow {
+				if !tc.p(v) {
+					t.Errorf("expected '%v' to be allowed", v)
+				}
+			}
+			for _, v := range tc.deny {
+				if tc.p(v) {
+					t.Errorf("expected '%v' to be denied", v)
+				}
+			}
+		})
+	}
+
+	t.Run("with contract", func(t *testing.T) {
+		r := AttributeContract(Attributes{"method": "GET", "limit": "1000"}, Preconditions{
+			"method": OneOf("GET", "POST"),
+			"limit":  IntBetween(1, 100),
+		})
+		if r.Valid {
+			t.Error("expected contract to fail")
+		}
+		if _, ok := r.Attributes["method"]; !ok {
+			t.Error("expected method to pass")
+		}
+	})
+}
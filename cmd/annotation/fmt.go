@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/troublete/go-annotation/analyze"
+)
+
+// formatCommand formats the annotations of the go files passed (files or directories, traversed recursively); like
+// gofmt, files are rewritten in place unless listed (-l) or diffed (-d)
+func formatCommand(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	list := flags.Bool("l", false, "list files whose annotations differ from the canonical form, don't rewrite them")
+	diff := flags.Bool("d", false, "print diffs of the annotations differing from the canonical form, don't rewrite them")
+	sortKeys := flags.Bool("sort", false, "sort attributes by key, otherwise their order is preserved")
+	trailing := flags.Bool("trailing", false, "write a separator after the last attribute, otherwise trailing separators are removed")
+	marker := flags.String("marker", "", "marker required in front of annotations (e.g. @)")
+	namespaces := flags.String("namespaces", "", "comma separated list of namespaces to format")
+	grammar := flags.Int("grammar", analyze.GrammarV1.Version, "version of the annotation grammar (1 or 2)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: annotation fmt [flags] [path ...]")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+
+	g, ok := analyze.Grammars[*grammar]
+	if !ok {
+		slog.Error("unknown grammar version", "grammar", *grammar)
+		return 2
+	}
+
+	opts := analyze.FormatOptions{
+		Analyzer: analyze.Analyzer{
+			Marker:  *marker,
+			Grammar: g,
+		},
+		SortKeys:          *sortKeys,
+		TrailingSeparator: *trailing,
+	}
+	if *namespaces != "" {
+		opts.Analyzer.Namespaces = strings.Split(*namespaces, ",")
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	f := formatter{opts: opts, list: *list, diff: *diff, out: os.Stdout}
+	failed := false
+	for _, root := range paths {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				// like the go tool, hidden directories and testdata are skipped
+				if name := d.Name(); path != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata") {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if !strings.HasSuffix(path, ".go") {
+				return nil
+			}
+
+			if err := f.file(path); err != nil {
+				slog.Error(err.Error())
+				failed = true
+			}
+			return nil
+		})
+		if err != nil {
+			slog.Error(err.Error())
+			failed = true
+		}
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// formatter formats files and reports or writes the result
+type formatter struct {
+	opts       analyze.FormatOptions
+	list, diff bool
+	out        io.Writer
+}
+
+// file formats the annotations of a go file
+func (f formatter) file(path string) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	formatted, err := analyze.FormatSource(path, src, f.opts)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(src, formatted) {
+		return nil
+	}
+
+	if f.list {
+		fmt.Fprintln(f.out, path)
+	}
+	if f.diff {
+		fmt.Fprint(f.out, unifiedDiff(path, string(src), string(formatted)))
+	}
+	if f.list || f.diff {
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, formatted, info.Mode().Perm())
+}
+
+// diffContext is the number of unchanged lines around changes in diffs
+const diffContext = 3
+
+// unifiedDiff returns the diff of the sources in unified format; formatting only rewrites comments in place, so lines
+// are compared pairwise
+func unifiedDiff(path, a, b string) string {
+	al, bl := strings.SplitAfter(a, "\n"), strings.SplitAfter(b, "\n")
+	if len(al) != len(bl) {
+		return "" // can't happen, formatted annotations never span lines
+	}
+
+	var changed []int
+	for idx := range al {
+		if al[idx] != bl[idx] {
+			changed = append(changed, idx)
+		}
+	}
+	if len(changed) == 0 {
+		return ""
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", path, path)
+	for len(changed) > 0 {
+		// a hunk spans all changes with less than twice the context of unchanged lines in between
+		n := 1
+		for n < len(changed) && changed[n]-changed[n-1] <= 2*diffContext {
+			n++
+		}
+		hunk := changed[:n]
+		changed = changed[n:]
+
+		start := max(hunk[0]-diffContext, 0)
+		end := min(hunk[len(hunk)-1]+diffContext+1, len(al))
+		if al[end-1] == "" {
+			end-- // the empty remainder after the final newline
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", start+1, end-start, start+1, end-start)
+
+		for idx := start; idx < end; {
+			if al[idx] == bl[idx] {
+				out.WriteString(" " + al[idx])
+				idx++
+				continue
+			}
+
+			run := idx
+			for run < end && al[run] != bl[run] {
+				run++
+			}
+			for _, l := range al[idx:run] {
+				out.WriteString("-" + l)
+			}
+			for _, l := range bl[idx:run] {
+				out.WriteString("+" + l)
+			}
+			idx = run
+		}
+	}
+	return out.String()
+}
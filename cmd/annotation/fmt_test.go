@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSource = `package demo
+
+// crud.model{name="users"}
+type User struct {
+	ID   string
+	Name string
+	Mail string
+	Age  int
+	Tags []string
+	// crud.field {name="tags",nullable}
+	Misc string
+}
+`
+
+func Test_Formatter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "user.go")
+	if err := os.WriteFile(path, []byte(testSource), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	if err := (formatter{list: true, diff: true, out: &b}).file(path); err != nil {
+		t.Fatal(err)
+	}
+
+	want := path + "\n" +
+		"--- " + path + "\n" +
+		"+++ " + path + "\n" +
+		"@@ -1,6 +1,6 @@\n" +
+		" package demo\n" +
+		" \n" +
+		"-// crud.model{name=\"users\"}\n" +
+		"+// crud.model{name=users}\n" +
+		" type User struct {\n" +
+		" \tID   string\n" +
+		" \tName string\n" +
+		"@@ -7,6 +7,6 @@\n" +
+		" \tMail string\n" +
+		" \tAge  int\n" +
+		" \tTags []string\n" +
+		"-\t// crud.field {name=\"tags\",nullable}\n" +
+		"+\t// crud.field{name=tags,nullable}\n" +
+		" \tMisc string\n" +
+		" }\n"
+	if b.String() != want {
+		t.Errorf("unexpected output:\n%s", b.String())
+	}
+
+	if src, _ := os.ReadFile(path); string(src) != testSource {
+		t.Error("expected file to be untouched when listing or diffing")
+	}
+
+	if err := (formatter{}).file(path); err != nil {
+		t.Fatal(err)
+	}
+	src, _ := os.ReadFile(path)
+	if !strings.Contains(string(src), "// crud.model{name=users}") || !strings.Contains(string(src), "// crud.field{name=tags,nullable}") {
+		t.Errorf("expected file to be rewritten:\n%s", src)
+	}
+
+	b.Reset()
+	if err := (formatter{list: true, out: &b}).file(path); err != nil || b.Len() > 0 {
+		t.Errorf("expected formatted file not to be listed (%v, %v)", b.String(), err)
+	}
+}
@@ -0,0 +1,13 @@
+// Command annotationcheck runs the annotation analyzer standalone or as vet tool
+// (`go vet -vettool=$(which annotationcheck) ./...`)
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/troublete/go-annotation/annotationcheck"
+)
+
+func main() {
+	singlechecker.Main(annotationcheck.Analyzer)
+}
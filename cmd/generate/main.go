@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/troublete/go-annotation/analyze"
+	"github.com/troublete/go-annotation/annotation"
+	"github.com/troublete/go-annotation/generate"
+	"github.com/troublete/go-annotation/inspect"
+	"github.com/troublete/go-annotation/internal/watch"
+)
+
+func main() {
+	root := flag.String("root", "./", "root path for inspection")
+	tmpl := flag.String("template", "", "path of the text/template file to execute")
+	out := flag.String("out", "", "path of the output file; Go files (.go) are formatted and get the imports requested")
+	pkg := flag.String("package", "", "package name of the output file (defaults to the name of its directory)")
+	marker := flag.String("marker", "", "marker required in front of annotations (e.g. @)")
+	namespaces := flag.String("namespaces", "", "comma separated list of allowed annotation namespaces")
+	grammar := flag.Int("grammar", analyze.GrammarV1.Version, "version of the annotation grammar (1 or 2)")
+	schemas := flag.String("schemas", "", "root path to discover annotation schemas from; enables validation")
+	strict := flag.String("strict", annotation.FailOnWarning.String(), "fail on diagnostics of level: warning, error or never")
+	watchRoot := flag.Bool("watch", false, "keep watching the root and regenerate whenever go files change")
+	interval := flag.Duration("interval", inspect.DefaultWatchInterval, "polling interval of -watch")
+	flag.Parse()
+
+	if *tmpl == "" || *out == "" {
+		slog.Error("-template and -out are required.")
+		os.Exit(1)
+	}
+
+	t, err := generate.ParseFile(*tmpl)
+	if err != nil {
+		slog.Error("failed to parse template", "err", err)
+		os.Exit(1)
+	}
+
+	g, ok := analyze.Grammars[*grammar]
+	if !ok {
+		slog.Error("unknown grammar version", "grammar", *grammar)
+		os.Exit(1)
+	}
+
+	strictness, err := annotation.ParseStrictness(*strict)
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+
+	a := analyze.Analyzer{
+		Marker:  *marker,
+		Grammar: g,
+	}
+	if *namespaces != "" {
+		a.Namespaces = strings.Split(*namespaces, ",")
+	}
+
+	opts := annotation.Options{
+		Analyzer:   a,
+		Strictness: strictness,
+	}
+
+	emit := func(r *annotation.Result) error {
+		if err := t.WriteFile(r, *pkg, *out); err != nil {
+			return fmt.Errorf("failed to generate from '%v': %w", *tmpl, err)
+		}
+
+		slog.Info("generated", "template", *tmpl, "out", *out)
+		return nil
+	}
+
+	if *watchRoot {
+		slog.Info("watching structure", "root", *root)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		// the output is regenerated from the template read at start, the template itself isn't watched
+		if err := watch.Run(ctx, watch.Options{Root: *root, Interval: *interval, Schemas: *schemas, Read: opts}, emit); err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *schemas != "" {
+		opts.Registry, err = annotation.LoadSchemas(*schemas, opts.Analyzer)
+		if err != nil {
+			slog.Error("failed to load schemas", "err", err)
+			os.Exit(1)
+		}
+	}
+
+	slog.Info("inspecting structure", "root", *root)
+
+	types, err := inspect.FindAllTypes(*root)
+	if err != nil {
+		slog.Error("failed to find all types", "err", err)
+		os.Exit(1)
+	}
+
+	funcs, err := inspect.FindAllFunctions(*root)
+	if err != nil {
+		slog.Error("failed to find all funcs", "err", err)
+		os.Exit(1)
+	}
+
+	r, err := annotation.ReadWithOptions(types, funcs, opts)
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+
+	if err := emit(r); err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+
+	"github.com/troublete/go-annotation/analyze"
+	"github.com/troublete/go-annotation/annotation"
+)
+
+// formats are the output formats; json and yaml render the whole output, the others render annotated specs
+var formats = map[string]func(w io.Writer, output any, specs []annotation.Match) error{
+	"json":   writeJSON,
+	"ndjson": writeNDJSON,
+	"yaml":   writeYAML,
+	"csv":    writeCSV,
+	"table":  writeTable,
+}
+
+// formatNames returns the names of all formats, sorted
+func formatNames() []string {
+	var names []string
+	for n := range formats {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// annotatedSpecs returns only the specs with annotations
+func annotatedSpecs(ms []annotation.Match) []annotation.Match {
+	var specs []annotation.Match
+	for _, m := range ms {
+		if len(m.Annotations) > 0 {
+			specs = append(specs, m)
+		}
+	}
+	return specs
+}
+
+func writeJSON(w io.Writer, output any, _ []annotation.Match) error {
+	c, err := json.MarshalIndent(output, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(w, bytes.NewBuffer(c).String())
+	return err
+}
+
+// specRecord is an annotated spec flattened into a single record
+type specRecord struct {
+	Target      analyze.Target         `json:"target"`
+	Reference   string                 `json:"reference"`
+	Package     string                 `json:"package"`
+	Name        string                 `json:"name"`
+	FilePath    string                 `json:"file_path"`
+	Line        int                    `json:"line"`
+	Annotations analyze.DefinitionList `json:"annotations"`
+}
+
+// writeNDJSON writes one annotated spec per line
+func writeNDJSON(w io.Writer, _ any, specs []annotation.Match) error {
+	e := json.NewEncoder(w)
+	for _, m := range specs {
+		p := m.Position()
+		if err := e.Encode(specRecord{
+			Target:      m.Target,
+			Reference:   m.Reference,
+			Package:     m.Package,
+			Name:        m.Name,
+			FilePath:    p.File,
+			Line:        p.Line,
+			Annotations: m.Annotations,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeCSV writes one row per attribute of every annotation (or one row per annotation without attributes)
+func writeCSV(w io.Writer, _ any, specs []annotation.Match) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"spec", "target", "identifier", "key", "value"}); err != nil {
+		return err
+	}
+
+	for _, m := range specs {
+		for _, d := range m.Annotations {
+			keys := sortedKeys(d.Arguments)
+			if len(keys) == 0 {
+				keys = []string{""}
+			}
+
+			for _, k := range keys {
+				if err := cw.Write([]string{m.Reference, string(m.Target), d.Identifier, k, d.Arguments[k]}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeTable writes a human-readable table of one annotation per row
+func writeTable(w io.Writer, _ any, specs []annotation.Match) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SPEC\tTARGET\tANNOTATION\tATTRIBUTES")
+	for _, m := range specs {
+		for _, d := range m.Annotations {
+			var attributes []string
+			for _, k := range sortedKeys(d.Arguments) {
+				if d.Arguments[k] == analyze.TrueString {
+					attributes = append(attributes, k)
+					continue
+				}
+				attributes = append(attributes, fmt.Sprintf("%s=%s", k, d.Arguments[k]))
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", m.Reference, m.Target, d.Identifier, strings.Join(attributes, " "))
+		}
+	}
+	return tw.Flush()
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// writeYAML writes the output as YAML, following the JSON encoding of the output (field names, omitted fields)
+func writeYAML(w io.Writer, output any, _ []annotation.Match) error {
+	c, err := json.Marshal(output)
+	if err != nil {
+		return err
+	}
+
+	d := json.NewDecoder(bytes.NewReader(c))
+	d.UseNumber()
+
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	yamlValue(&b, v, 0)
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// yamlValue writes a value in block style; scalars are written inline, collections on the following lines
+func yamlValue(b *strings.Builder, v any, indent int) {
+	pad := strings.Repeat("  ", indent)
+	switch v := v.(type) {
+	case map[string]any:
+		if len(v) == 0 {
+			b.WriteString("{}\n")
+			return
+		}
+
+		var keys []string
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			b.WriteString(pad + yamlString(k) + ":")
+			yamlNested(b, v[k], indent)
+		}
+	case []any:
+		if len(v) == 0 {
+			b.WriteString("[]\n")
+			return
+		}
+
+		for _, item := range v {
+			if !yamlCollection(item) {
+				b.WriteString(pad + "-")
+				yamlNested(b, item, indent)
+				continue
+			}
+
+			// collections start on the line of the dash, in place of their indentation
+			var nested strings.Builder
+			yamlValue(&nested, item, indent+1)
+			b.WriteString(pad + "- " + strings.TrimPrefix(nested.String(), pad+"  "))
+		}
+	default:
+		b.WriteString(yamlScalar(v) + "\n")
+	}
+}
+
+// yamlCollection returns if the value is a non-empty collection, written in block style
+func yamlCollection(v any) bool {
+	switch c := v.(type) {
+	case map[string]any:
+		return len(c) > 0
+	case []any:
+		return len(c) > 0
+	}
+	return false
+}
+
+// yamlNested writes the value of a key or list item, which was already written
+func yamlNested(b *strings.Builder, v any, indent int) {
+	if yamlCollection(v) {
+		b.WriteString("\n")
+		yamlValue(b, v, indent+1)
+		return
+	}
+
+	b.WriteString(" ")
+	yamlValue(b, v, indent+1)
+}
+
+func yamlScalar(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	case string:
+		return yamlString(v)
+	}
+	return fmt.Sprint(v)
+}
+
+// yamlPlainReserved are strings which are read as other scalars if not quoted
+var yamlPlainReserved = []string{"", "~", "null", "true", "false", "yes", "no", "on", "off", "y", "n"}
+
+// yamlString quotes strings unless they are a plain word (a letter followed by letters, digits or any of `_-./`), as
+// YAML resolves many other forms as something else than the same string (e.g. 0x10, 1_000 or 2024-01-01)
+func yamlString(s string) string {
+	if slices.Contains(yamlPlainReserved, strings.ToLower(s)) {
+		return strconv.Quote(s)
+	}
+	for idx, r := range s {
+		plain := unicode.IsLetter(r) || (idx > 0 && (unicode.IsDigit(r) || strings.ContainsRune("_-./", r)))
+		if !plain {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/troublete/go-annotation/analyze"
+	"github.com/troublete/go-annotation/annotation"
+)
+
+func testSpecs() []annotation.Match {
+	return []annotation.Match{
+		{
+			Target:    analyze.TargetField,
+			Reference: "model.User.ID",
+			Annotations: analyze.DefinitionList{
+				{Identifier: "crud.field", Arguments: map[string]string{"name": "id", "primary": analyze.TrueString}},
+				{Identifier: "crud.index", Arguments: map[string]string{}},
+			},
+		},
+	}
+}
+
+func Test_WriteCSV(t *testing.T) {
+	var b strings.Builder
+	if err := writeCSV(&b, nil, testSpecs()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "spec,target,identifier,key,value\n" +
+		"model.User.ID,field,crud.field,name,id\n" +
+		"model.User.ID,field,crud.field,primary,TRUE\n" +
+		"model.User.ID,field,crud.index,,\n"
+	if b.String() != want {
+		t.Errorf("unexpected csv\n%v", b.String())
+	}
+}
+
+func Test_WriteTable(t *testing.T) {
+	var b strings.Builder
+	if err := writeTable(&b, nil, testSpecs()); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 3 || !strings.HasSuffix(lines[1], "crud.field  name=id primary") {
+		t.Errorf("unexpected table\n%v", b.String())
+	}
+}
+
+func Test_WriteYAML(t *testing.T) {
+	var b strings.Builder
+	err := writeYAML(&b, map[string]any{
+		"types": []any{
+			map[string]any{"name": "User", "line": 4, "fields": []any{}},
+		},
+		"values": []any{
+			"true", "1.5", "a: b", "", "plain", nil, false,
+			"0x10", "0o17", "1_000", "2024-01-01", "crud.field", "-", "a b",
+		},
+		"nothing": map[string]any{},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `nothing: {}
+types:
+  - fields: []
+    line: 4
+    name: User
+values:
+  - "true"
+  - "1.5"
+  - "a: b"
+  - ""
+  - plain
+  - null
+  - false
+  - "0x10"
+  - "0o17"
+  - "1_000"
+  - "2024-01-01"
+  - crud.field
+  - "-"
+  - "a b"
+`
+	if b.String() != want {
+		t.Errorf("unexpected yaml\n%v", b.String())
+	}
+}
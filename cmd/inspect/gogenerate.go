@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/troublete/go-annotation/analyze"
+	"github.com/troublete/go-annotation/inspect"
+)
+
+const (
+	// scopePackage scopes inspection to the package of the file holding the go:generate directive
+	scopePackage = "package"
+	// scopeDecl scopes inspection to the declaration (type or function) right below the go:generate directive
+	scopeDecl = "decl"
+)
+
+// formatExtensions are the file extensions of the output formats
+var formatExtensions = map[string]string{
+	"json":   ".json",
+	"ndjson": ".ndjson",
+	"yaml":   ".yaml",
+	"csv":    ".csv",
+	"table":  ".txt",
+}
+
+// goGenerate is the environment set by go generate when running a directive; the working directory is the directory
+// of the package
+type goGenerate struct {
+	File    string
+	Package string
+	Line    int
+}
+
+// goGenerateEnv reads the environment set by go generate
+func goGenerateEnv() (goGenerate, error) {
+	g := goGenerate{
+		File:    os.Getenv("GOFILE"),
+		Package: os.Getenv("GOPACKAGE"),
+	}
+	if g.File == "" || g.Package == "" {
+		return g, fmt.Errorf("not invoked by go generate, GOFILE and GOPACKAGE are not set")
+	}
+
+	line, err := strconv.Atoi(os.Getenv("GOLINE"))
+	if err != nil {
+		return g, fmt.Errorf("invalid GOLINE: %w", err)
+	}
+	g.Line = line
+	return g, nil
+}
+
+// scope returns the types and functions of the package, or of the declaration below the directive; the name of the
+// declaration is returned as well
+func (g goGenerate) scope(scope string, types inspect.TypeList, funcs inspect.FunctionList) (inspect.TypeList, inspect.FunctionList, string, error) {
+	var scopedTypes inspect.TypeList
+	for _, t := range types {
+		if t.Package == g.Package {
+			scopedTypes = append(scopedTypes, t)
+		}
+	}
+
+	var scopedFuncs inspect.FunctionList
+	for _, f := range funcs {
+		if f.Package == g.Package {
+			scopedFuncs = append(scopedFuncs, f)
+		}
+	}
+
+	if scope == scopePackage {
+		return scopedTypes, scopedFuncs, "", nil
+	}
+
+	// the declaration below is the first one of the file following the directive (its doc comment may be in between)
+	var declType *inspect.Type
+	var declFunc *inspect.Function
+	line := 0
+	below := func(filePath string, l int) bool {
+		return filepath.Base(filePath) == g.File && l > g.Line && (line == 0 || l < line)
+	}
+	for idx, t := range scopedTypes {
+		if below(t.FilePath, t.Line) {
+			declType, declFunc, line = &scopedTypes[idx], nil, t.Line
+		}
+	}
+	for idx, f := range scopedFuncs {
+		if below(f.FilePath, f.Line) {
+			declType, declFunc, line = nil, &scopedFuncs[idx], f.Line
+		}
+	}
+
+	switch {
+	case declType != nil:
+		return inspect.TypeList{*declType}, nil, declType.Name, nil
+	case declFunc != nil:
+		name := declFunc.Name
+		if declFunc.Receiver != nil {
+			name = declFunc.Receiver.ReceiverType + "_" + name
+		}
+		return nil, inspect.FunctionList{*declFunc}, name, nil
+	}
+	return nil, nil, "", fmt.Errorf("no declaration found below %v:%v", g.File, g.Line)
+}
+
+// output returns the path of the output file next to the file holding the directive (e.g. `user_annotations.json`
+// or, scoped to a declaration, `user_user_annotations.json`)
+func (g goGenerate) output(decl, format string) string {
+	name := strings.TrimSuffix(g.File, filepath.Ext(g.File))
+	if decl != "" {
+		name += "_" + analyze.AttributeName(decl)
+	}
+	return name + "_annotations" + formatExtensions[format]
+}
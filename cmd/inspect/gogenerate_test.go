@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/troublete/go-annotation/inspect"
+)
+
+func Test_GoGenerateScope(t *testing.T) {
+	types := inspect.TypeList{
+		{Name: "User", Package: "model", FilePath: "user.go", Line: 12},
+		{Name: "Account", Package: "model", FilePath: "account.go", Line: 5},
+		{Name: "Fixture", Package: "model_test", FilePath: "user_test.go", Line: 14},
+	}
+	funcs := inspect.FunctionList{
+		{Name: "Save", Package: "model", FilePath: "user.go", Line: 20, Receiver: &inspect.Receiver{ReceiverType: "User"}},
+		{Name: "New", Package: "model", FilePath: "user.go", Line: 4},
+	}
+
+	g := goGenerate{File: "user.go", Package: "model", Line: 10}
+
+	ts, fs, decl, err := g.scope(scopePackage, types, funcs)
+	if err != nil || len(ts) != 2 || len(fs) != 2 || decl != "" {
+		t.Errorf("unexpected package scope (types=%v, funcs=%v, decl=%v, err=%v)", len(ts), len(fs), decl, err)
+	}
+
+	ts, fs, decl, err = g.scope(scopeDecl, types, funcs)
+	if err != nil || len(ts) != 1 || ts[0].Name != "User" || len(fs) != 0 || decl != "User" {
+		t.Errorf("unexpected decl scope (types=%v, funcs=%v, decl=%v, err=%v)", ts, fs, decl, err)
+	}
+	if out := g.output(decl, "yaml"); out != "user_user_annotations.yaml" {
+		t.Errorf("unexpected output %v", out)
+	}
+
+	g.Line = 15
+	ts, fs, decl, err = g.scope(scopeDecl, types, funcs)
+	if err != nil || len(ts) != 0 || len(fs) != 1 || decl != "User_Save" {
+		t.Errorf("unexpected decl scope (types=%v, funcs=%v, decl=%v, err=%v)", ts, fs, decl, err)
+	}
+	if out := g.output(decl, "json"); out != "user_user_save_annotations.json" {
+		t.Errorf("unexpected output %v", out)
+	}
+
+	g.Line = 30
+	if _, _, _, err := g.scope(scopeDecl, types, funcs); err == nil {
+		t.Error("expected error without declaration below")
+	}
+
+	if out := g.output("", "table"); out != "user_annotations.txt" {
+		t.Errorf("unexpected output %v", out)
+	}
+}
@@ -2,50 +2,202 @@ package main
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
 	"flag"
 	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
+	"strings"
 
+	"github.com/troublete/go-annotation/analyze"
 	"github.com/troublete/go-annotation/annotation"
 	"github.com/troublete/go-annotation/inspect"
+	"github.com/troublete/go-annotation/internal/watch"
 )
 
 func main() {
 	root := flag.String("root", "./", "root path for inspection")
+	marker := flag.String("marker", "", "marker required in front of annotations (e.g. @)")
+	namespaces := flag.String("namespaces", "", "comma separated list of allowed annotation namespaces")
+	grammar := flag.Int("grammar", analyze.GrammarV1.Version, "version of the annotation grammar (1 or 2)")
+	directives := flag.Bool("directives", false, "extract directives (e.g. //go:generate) alongside annotations")
+	strict := flag.String("strict", annotation.FailOnWarning.String(), "fail on diagnostics of level: warning, error or never")
+	schemas := flag.String("schemas", "", "root path to discover annotation schemas from to validate against (defaults to -root)")
+	query := flag.String("query", "", "selector of the specs to output (e.g. 'type[crud.model] > field[crud.field name=id]')")
+	format := flag.String("format", "json", fmt.Sprintf("output format: %v", strings.Join(formatNames(), ", ")))
+	scope := flag.String("scope", "", "when invoked by go generate, scope to the package or the decl right below the directive")
+	out := flag.String("out", "", "path of the output file (defaults to stdout, or to a file next to $GOFILE if scoped)")
+	watchRoot := flag.Bool("watch", false, "keep watching the root and re-emit the output whenever go files change")
+	interval := flag.Duration("interval", inspect.DefaultWatchInterval, "polling interval of -watch")
 	flag.Parse()
 
-	slog.Info("inspecting structure", "root", *root)
+	findTypes, findFuncs := inspect.FindAllTypes, inspect.FindAllFunctions
+
+	var gen goGenerate
+	if *scope != "" {
+		if *scope != scopePackage && *scope != scopeDecl {
+			slog.Error("unknown scope", "scope", *scope)
+			os.Exit(1)
+		}
+		if *watchRoot {
+			slog.Error("-watch can't be combined with -scope")
+			os.Exit(1)
+		}
+
+		var err error
+		gen, err = goGenerateEnv()
+		if err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+
+		// go generate runs in the directory of the package
+		*root = "."
+		findTypes, findFuncs = inspect.FindPackageTypes, inspect.FindPackageFunctions
+	}
 
 	if *root == "" {
 		slog.Error("-root is required.")
 		os.Exit(1)
 	}
+	if *schemas == "" {
+		*schemas = *root
+	}
+
+	g, ok := analyze.Grammars[*grammar]
+	if !ok {
+		slog.Error("unknown grammar version", "grammar", *grammar)
+		os.Exit(1)
+	}
 
-	types, err := inspect.FindAllTypes(*root)
+	strictness, err := annotation.ParseStrictness(*strict)
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+
+	a := analyze.Analyzer{
+		Marker:  *marker,
+		Grammar: g,
+	}
+	if *namespaces != "" {
+		a.Namespaces = strings.Split(*namespaces, ",")
+	}
+
+	write, ok := formats[*format]
+	if !ok {
+		slog.Error("unknown format", "format", *format)
+		os.Exit(1)
+	}
+
+	var selector annotation.Selector
+	if *query != "" {
+		selector, err = annotation.ParseSelector(*query)
+		if err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	opts := annotation.Options{
+		Analyzer:   a,
+		Directives: *directives,
+		Strictness: strictness,
+	}
+
+	emit := func(def *annotation.Result) error {
+		var output any = def
+		specs := annotatedSpecs(annotation.NewIndex(def).Find(annotation.Query{}))
+		if selector != nil {
+			specs = selector.Select(annotation.NewIndex(def))
+			if specs == nil {
+				specs = []annotation.Match{}
+			}
+			output = specs
+		}
+
+		// the output is rendered first, so a failure doesn't leave a truncated output file behind
+		var b bytes.Buffer
+		if err := write(&b, output, specs); err != nil {
+			return err
+		}
+
+		if *out == "" {
+			_, err := b.WriteTo(os.Stdout)
+			return err
+		}
+
+		if err := os.WriteFile(*out, b.Bytes(), 0o644); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+		slog.Info("written", "out", *out)
+		return nil
+	}
+
+	if *watchRoot {
+		slog.Info("watching structure", "root", *root)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		if err := watch.Run(ctx, watch.Options{Root: *root, Interval: *interval, Schemas: *schemas, Read: opts}, emit); err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	slog.Info("inspecting structure", "root", *root)
+
+	types, err := findTypes(*root)
 	if err != nil {
 		slog.Error("failed to find all types", "err", err)
 		os.Exit(1)
 	}
 
-	funcs, err := inspect.FindAllFunctions(*root)
+	funcs, err := findFuncs(*root)
 	if err != nil {
 		slog.Error("failed to find all funcs", "err", err)
 		os.Exit(1)
 	}
 
-	def, err := annotation.Read(types, funcs)
+	if *scope != "" {
+		var decl string
+		types, funcs, decl, err = gen.scope(*scope, types, funcs)
+		if err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+
+		if *out == "" {
+			*out = gen.output(decl, *format)
+		}
+	}
+
+	registry, err := annotation.LoadSchemas(*schemas, opts.Analyzer)
 	if err != nil {
-		slog.Error(err.Error())
+		slog.Error("failed to load schemas", "err", err)
 		os.Exit(1)
 	}
+	// without any schema declared besides the meta schemas nothing is validated, otherwise every annotation would be
+	// reported as unregistered
+	if len(registry.Schemas()) > len(annotation.MetaSchemas) {
+		opts.Registry = registry
+	}
 
-	c, err := json.MarshalIndent(def, "", "\t")
+	def, err := annotation.ReadWithOptions(types, funcs, opts)
 	if err != nil {
 		slog.Error(err.Error())
 		os.Exit(1)
 	}
 
-	fmt.Println(bytes.NewBuffer(c).String())
+	for _, d := range def.Diagnostics {
+		watch.LogDiagnostic(d)
+	}
+
+	if err := emit(def); err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/troublete/go-annotation/analyze"
+	"github.com/troublete/go-annotation/annotation"
+)
+
+func main() {
+	kind := flag.String("kind", "result", "schema to emit: result (output of cmd/inspect) or annotations (registered annotation attributes)")
+	schemas := flag.String("schemas", "", "root path to discover annotation schemas from")
+	marker := flag.String("marker", "", "marker required in front of annotations (e.g. @)")
+	grammar := flag.Int("grammar", analyze.GrammarV1.Version, "version of the annotation grammar (1 or 2)")
+	flag.Parse()
+
+	g, ok := analyze.Grammars[*grammar]
+	if !ok {
+		slog.Error("unknown grammar version", "grammar", *grammar)
+		os.Exit(1)
+	}
+
+	registry := &analyze.Registry{}
+	if *schemas != "" {
+		slog.Info("loading schemas", "root", *schemas)
+
+		var err error
+		registry, err = annotation.LoadSchemas(*schemas, analyze.Analyzer{Marker: *marker, Grammar: g})
+		if err != nil {
+			slog.Error("failed to load schemas", "err", err)
+			os.Exit(1)
+		}
+	}
+
+	var js map[string]any
+	switch *kind {
+	case "result":
+		js = annotation.ResultJSONSchema(registry)
+	case "annotations":
+		js = registry.JSONSchema()
+	default:
+		slog.Error("unknown schema kind", "kind", *kind)
+		os.Exit(1)
+	}
+
+	c, err := json.MarshalIndent(js, "", "\t")
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+
+	fmt.Println(bytes.NewBuffer(c).String())
+}
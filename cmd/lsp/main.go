@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/troublete/go-annotation/analyze"
+	"github.com/troublete/go-annotation/lsp"
+)
+
+func main() {
+	schemas := flag.String("schemas", "", "root path to discover annotation schemas from (defaults to the workspace root)")
+	marker := flag.String("marker", "", "marker required in front of annotations (e.g. @)")
+	namespaces := flag.String("namespaces", "", "comma separated list of allowed annotation namespaces")
+	grammar := flag.Int("grammar", analyze.GrammarV1.Version, "version of the annotation grammar (1 or 2)")
+	flag.Parse()
+
+	g, ok := analyze.Grammars[*grammar]
+	if !ok {
+		slog.Error("unknown grammar version", "grammar", *grammar)
+		os.Exit(1)
+	}
+
+	a := analyze.Analyzer{
+		Marker:  *marker,
+		Grammar: g,
+	}
+	if *namespaces != "" {
+		a.Namespaces = strings.Split(*namespaces, ",")
+	}
+
+	// stdout is reserved for the protocol, logs are written to stderr
+	if err := lsp.Serve(os.Stdin, os.Stdout, lsp.Options{Analyzer: a, Schemas: *schemas}); err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+}
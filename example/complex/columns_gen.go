@@ -0,0 +1,22 @@
+// Code generated by cmd/generate from columns.go.tmpl. DO NOT EDIT.
+
+package complex
+
+import "strings"
+
+// UserTable is the table User is persisted in
+const UserTable = "users"
+
+// UserColumns are the columns of User
+var UserColumns = []string{
+	"id",
+	"firstname",
+	"lastname",
+	"created_at",
+	"deleted_at",
+}
+
+// SelectUser returns the select statement of all columns of User
+func SelectUser() string {
+	return "SELECT " + strings.Join(UserColumns, ", ") + " FROM " + UserTable
+}
@@ -0,0 +1,19 @@
+package complex
+
+// Model marks a struct type as persisted model
+// annotation.schema{name="crud.model",targets="type"}
+type Model struct {
+	// Name of the table
+	// annotation.attr{required}
+	Name string
+}
+
+// Field marks a struct field as persisted column
+// annotation.schema{name="crud.field",targets="field"}
+type Field struct {
+	// Name of the column
+	// annotation.attr{required}
+	Name string
+	// annotation.attr{default=false}
+	Primary bool
+}
@@ -3,6 +3,8 @@ package complex
 import "time"
 
 // crud.model{name="users"}
+//
+//go:generate go run ../../cmd/inspect -scope decl -format yaml
 type User struct {
 	// crud.field{name="id"}
 	ID *string
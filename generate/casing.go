@@ -0,0 +1,84 @@
+package generate
+
+import (
+	"strings"
+	"unicode"
+)
+
+// initialisms are written in a single case in Go identifiers
+var initialisms = map[string]bool{
+	"API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true, "EOF": true, "GUID": true, "HTML": true,
+	"HTTP": true, "HTTPS": true, "ID": true, "IP": true, "JSON": true, "RPC": true, "SQL": true, "SSH": true,
+	"TCP": true, "TLS": true, "TTL": true, "UDP": true, "UI": true, "UID": true, "URI": true, "URL": true,
+	"UUID": true, "XML": true,
+}
+
+// words splits an identifier into words on separators (`_`, `-`, `.`, whitespace) and case changes (e.g. `HTTPServer`
+// into `HTTP` and `Server`)
+func words(s string) []string {
+	var ws []string
+	var current []rune
+	flush := func() {
+		if len(current) > 0 {
+			ws = append(ws, string(current))
+			current = nil
+		}
+	}
+
+	runes := []rune(s)
+	for idx, r := range runes {
+		if r == '_' || r == '-' || r == '.' || unicode.IsSpace(r) {
+			flush()
+			continue
+		}
+
+		if idx > 0 && unicode.IsUpper(r) {
+			prev := runes[idx-1]
+			nextLower := idx+1 < len(runes) && unicode.IsLower(runes[idx+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+	return ws
+}
+
+// capitalize upper cases the first letter and lower cases the rest of a word, initialisms are upper cased
+func capitalize(w string) string {
+	if initialisms[strings.ToUpper(w)] {
+		return strings.ToUpper(w)
+	}
+
+	runes := []rune(strings.ToLower(w))
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// Pascal converts to an exported Go identifier (e.g. `user_id` to `UserID`)
+func Pascal(s string) string {
+	var b strings.Builder
+	for _, w := range words(s) {
+		b.WriteString(capitalize(w))
+	}
+	return b.String()
+}
+
+// Camel converts to an unexported Go identifier (e.g. `user_id` to `userID`, `ID` to `id`)
+func Camel(s string) string {
+	var b strings.Builder
+	for idx, w := range words(s) {
+		if idx == 0 {
+			b.WriteString(strings.ToLower(w))
+			continue
+		}
+		b.WriteString(capitalize(w))
+	}
+	return b.String()
+}
+
+// Kebab converts to kebab case (e.g. `UserID` to `user-id`)
+func Kebab(s string) string {
+	return strings.ToLower(strings.Join(words(s), "-"))
+}
@@ -0,0 +1,30 @@
+package generate
+
+import "testing"
+
+func Test_Casing(t *testing.T) {
+	for _, tc := range []struct {
+		in, pascal, camel, kebab string
+	}{
+		{in: "user_id", pascal: "UserID", camel: "userID", kebab: "user-id"},
+		{in: "UserID", pascal: "UserID", camel: "userID", kebab: "user-id"},
+		{in: "HTTPServer", pascal: "HTTPServer", camel: "httpServer", kebab: "http-server"},
+		{in: "crud.model", pascal: "CrudModel", camel: "crudModel", kebab: "crud-model"},
+		{in: "created at", pascal: "CreatedAt", camel: "createdAt", kebab: "created-at"},
+		{in: "ID", pascal: "ID", camel: "id", kebab: "id"},
+		{in: "v2Api", pascal: "V2API", camel: "v2API", kebab: "v2-api"},
+		{in: "", pascal: "", camel: "", kebab: ""},
+	} {
+		t.Run(tc.in, func(t *testing.T) {
+			for _, c := range []struct{ has, want string }{
+				{Pascal(tc.in), tc.pascal},
+				{Camel(tc.in), tc.camel},
+				{Kebab(tc.in), tc.kebab},
+			} {
+				if c.has != c.want {
+					t.Errorf("unexpected casing (has=%v, want=%v)", c.has, c.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+// Package generate renders text/template files against the annotations of a result, e.g. to generate Go code
+package generate
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/troublete/go-annotation/analyze"
+	"github.com/troublete/go-annotation/annotation"
+	"github.com/troublete/go-annotation/internal/gofile"
+)
+
+// Data is passed to templates on execution
+type Data struct {
+	*annotation.Result
+
+	// Package is the package name of the generated file
+	Package string
+}
+
+// Template is a text/template executed against a result; besides the builtin functions, templates can use the
+// functions documented on Funcs
+type Template struct {
+	tmpl *template.Template
+}
+
+// execution holds the state of a single template execution
+type execution struct {
+	index   *annotation.Index
+	imports []gofile.Import
+}
+
+// Funcs returns the helper functions available in templates; helpers depending on the result are bound on execution
+//
+//   - casing: `pascal`, `camel`, `snake`, `kebab`, `lower`, `upper`
+//   - strings: `join`, `split`, `trim`, `quote`
+//   - imports: `import "path"`, `importAs "name" "path"`; added to the generated Go file
+//   - filtering: `find "crud.*"` (identifier pattern), `select "type[crud.model] > field"` (selector), `children
+//     $type "crud.field"`, returning matches
+//   - annotations: `annotation $match "crud.model"` (first annotation of the spec), `attr $annotation "name"`, `has
+//     $annotation "primary"`
+func Funcs() template.FuncMap {
+	return (&execution{index: annotation.NewIndex(&annotation.Result{})}).funcs()
+}
+
+func (e *execution) funcs() template.FuncMap {
+	return template.FuncMap{
+		"pascal": Pascal,
+		"camel":  Camel,
+		"snake":  analyze.AttributeName,
+		"kebab":  Kebab,
+		"lower":  strings.ToLower,
+		"upper":  strings.ToUpper,
+
+		"join":  func(sep string, s []string) string { return strings.Join(s, sep) },
+		"split": func(sep, s string) []string { return strings.Split(s, sep) },
+		"trim":  strings.TrimSpace,
+		"quote": func(s string) string { return fmt.Sprintf("%q", s) },
+
+		"import": func(path string) string {
+			e.imports = append(e.imports, gofile.Import{Path: path})
+			return ""
+		},
+		"importAs": func(name, path string) string {
+			e.imports = append(e.imports, gofile.Import{Name: name, Path: path})
+			return ""
+		},
+
+		"find": func(identifier string) []annotation.Match {
+			return e.index.Find(annotation.Query{Identifier: identifier})
+		},
+		"select": func(selector string) ([]annotation.Match, error) {
+			s, err := annotation.ParseSelector(selector)
+			if err != nil {
+				return nil, err
+			}
+			return s.Select(e.index), nil
+		},
+		"children": func(parent annotation.Match, identifier string) []annotation.Match {
+			if parent.Type == nil {
+				return nil
+			}
+			return e.index.Children(parent.Type, annotation.Query{Identifier: identifier})
+		},
+
+		"annotation": func(m annotation.Match, identifier string) *analyze.Definition {
+			for _, d := range m.AllAnnotations() {
+				if d.Identifier == identifier {
+					return &d
+				}
+			}
+			return nil
+		},
+		"attr": func(d *analyze.Definition, key string) string {
+			if d == nil {
+				return ""
+			}
+			return d.Arguments[key]
+		},
+		"has": func(d *analyze.Definition, key string) bool {
+			if d == nil {
+				return false
+			}
+			_, ok := d.Arguments[key]
+			return ok
+		},
+	}
+}
+
+// Parse parses a template
+func Parse(name, text string) (*Template, error) {
+	tmpl, err := template.New(name).Funcs(Funcs()).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{tmpl: tmpl}, nil
+}
+
+// ParseFile parses a template file
+func ParseFile(path string) (*Template, error) {
+	c, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(filepath.Base(path), string(c))
+}
+
+// execute executes the template against the result, returning the raw output and the imports requested
+func (t *Template) execute(r *annotation.Result, pkg string) ([]byte, []gofile.Import, error) {
+	e := &execution{index: annotation.NewIndex(r)}
+
+	tmpl, err := t.tmpl.Clone()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var b bytes.Buffer
+	if err := tmpl.Funcs(e.funcs()).Execute(&b, Data{Result: r, Package: pkg}); err != nil {
+		return nil, nil, err
+	}
+	return b.Bytes(), e.imports, nil
+}
+
+// Execute executes the template against the result and returns the output as is
+func (t *Template) Execute(r *annotation.Result, pkg string) ([]byte, error) {
+	out, _, err := t.execute(r, pkg)
+	return out, err
+}
+
+// ExecuteGo executes the template against the result, adds the imports requested and formats the output like gofmt;
+// the output must be a complete Go file
+func (t *Template) ExecuteGo(r *annotation.Result, pkg string) ([]byte, error) {
+	out, imports, err := t.execute(r, pkg)
+	if err != nil {
+		return nil, err
+	}
+	return gofile.Format(t.tmpl.Name(), out, imports...)
+}
+
+// WriteFile executes the template against the result and writes the output to path; Go files (.go) are executed with
+// ExecuteGo and the package name, if empty, defaults to the name of the directory of path
+func (t *Template) WriteFile(r *annotation.Result, pkg, path string) error {
+	if pkg == "" {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		pkg = filepath.Base(filepath.Dir(abs))
+	}
+
+	execute := t.Execute
+	if filepath.Ext(path) == ".go" {
+		execute = t.ExecuteGo
+	}
+
+	out, err := execute(r, pkg)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, out, 0o644)
+}
@@ -0,0 +1,135 @@
+package generate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/troublete/go-annotation/annotation"
+	"github.com/troublete/go-annotation/inspect"
+)
+
+func testResult(t *testing.T) *annotation.Result {
+	r, err := annotation.Read(inspect.TypeList{
+		{
+			Name:     "User",
+			Package:  "model",
+			Comments: []string{`crud.model{name=users}`},
+			Fields: []inspect.Field{
+				{Name: "ID", Comments: []string{`crud.field{name=id,primary}`}},
+				{Name: "Name", Comments: []string{`crud.field{name=name}`}},
+				{Name: "Internal"},
+			},
+		},
+		{
+			Name:    "Session",
+			Package: "model",
+		},
+	}, inspect.FunctionList{
+		{Name: "GetUser", Package: "api", Comments: []string{`chariot.route{method=GET,path="/users/{id}"}`}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func Test_TemplateExecute(t *testing.T) {
+	tmpl, err := Parse("test", `
+{{- range find "crud.*" }}{{ if .Type }}{{ snake .Name }}:{{ range children . "crud.field" }} {{ attr (annotation . "crud.field") "name" }}{{ if has (annotation . "crud.field") "primary" }}*{{ end }}{{ end }}{{ end }}{{ end }}
+{{ range select "func[chariot.route method=GET]" }}{{ .Name | kebab }} {{ attr (annotation . "chariot.route") "path" }}{{ end }}
+{{ .Package }} {{ len .Types }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := tmpl.Execute(testResult(t), "model")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "user: id* name\nget-user /users/{id}\nmodel 2"; string(out) != want {
+		t.Errorf("unexpected output (has=%q, want=%q)", out, want)
+	}
+}
+
+func Test_TemplateExecuteGo(t *testing.T) {
+	tmpl, err := Parse("test.go", `package {{ .Package }}
+{{ import "strings" }}{{ importAs "str" "strconv" }}{{ import "fmt" }}
+import "fmt"
+{{ range find "crud.model" }}
+func {{ .Name }}Table() string { return strings.ToUpper({{ attr (annotation . "crud.model") "name" | quote }}) + str.Itoa(1) + fmt.Sprint() }
+{{ end }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := tmpl.ExecuteGo(testResult(t), "model")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `package model
+
+import (
+	"fmt"
+	str "strconv"
+	"strings"
+)
+
+func UserTable() string { return strings.ToUpper("users") + str.Itoa(1) + fmt.Sprint() }
+`
+	if string(out) != want {
+		t.Errorf("unexpected output\n%v", string(out))
+	}
+}
+
+func Test_TemplateErrors(t *testing.T) {
+	if _, err := Parse("test", `{{ unknown }}`); err == nil {
+		t.Error("expected error on unknown function")
+	}
+
+	tmpl, err := Parse("test", `{{ select "struct[crud.model]" }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tmpl.Execute(testResult(t), ""); err == nil {
+		t.Error("expected error on invalid selector")
+	}
+
+	tmpl, err = Parse("test.go", `func {`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tmpl.ExecuteGo(testResult(t), ""); err == nil {
+		t.Error("expected error on invalid Go output")
+	}
+}
+
+func Test_TemplateWriteFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "model")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := ParseFile("../example/complex/columns.go.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "columns_gen.go")
+	if err := tmpl.WriteFile(testResult(t), "", path); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"package model", `import "strings"`, `const UserTable = "users"`, "func SelectUser() string"} {
+		if !strings.Contains(string(c), want) {
+			t.Errorf("expected %q in output\n%v", want, string(c))
+		}
+	}
+}
@@ -7,12 +7,15 @@ import (
 	"go/token"
 	"io/fs"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 )
 
 var (
-	gotypes = []string{
+	directiveRe = regexp.MustCompile(`^[a-z0-9]+:[a-z0-9]`)
+	gotypes     = []string{
 		"any",
 		"bool",
 		"byte",
@@ -44,17 +47,43 @@ type Receiver struct {
 }
 
 type Function struct {
-	Comments []string  `json:"-"`
-	FilePath string    `json:"file_path"`
-	Name     string    `json:"name"`
-	Package  string    `json:"package"`
-	Receiver *Receiver `json:"receiver,omitempty"`
+	Comments         []string  `json:"-"`
+	CommentLines     []int     `json:"-"`
+	LineComments     []string  `json:"-"`
+	LineCommentLines []int     `json:"-"`
+	Directives       []string  `json:"-"`
+	FileDirectives   []string  `json:"-"`
+	FilePath         string    `json:"file_path"`
+	Line             int       `json:"line"`
+	Name             string    `json:"name"`
+	Package          string    `json:"package"`
+	Receiver         *Receiver `json:"receiver,omitempty"`
 }
 
 func (f Function) Doc() []string {
 	return f.Comments
 }
 
+func (f Function) LineDoc() []string {
+	return f.LineComments
+}
+
+func (f Function) DirectiveDoc() []string {
+	return f.Directives
+}
+
+func (f Function) FileDirectiveDoc() []string {
+	return f.FileDirectives
+}
+
+func (f Function) Location() (string, int) {
+	return f.FilePath, f.Line
+}
+
+func (f Function) CommentLocations() ([]int, []int) {
+	return f.CommentLines, f.LineCommentLines
+}
+
 type FunctionList []Function
 
 // Find returns a function by name
@@ -74,10 +103,24 @@ func (f FunctionList) Find(name string) *Function {
 // To be used to use go code as metaprogramming input for code generation and similar
 // functions
 func FindAllFunctions(root string) (FunctionList, error) {
+	return findFunctions(root, true)
+}
+
+// FindPackageFunctions works like FindAllFunctions, but only extracts the functions of the package in dir, without
+// traversing sub directories
+func FindPackageFunctions(dir string) (FunctionList, error) {
+	return findFunctions(dir, false)
+}
+
+// parseDirs parses the go files in root and, if recursive, in all directories below
+func parseDirs(fset *token.FileSet, root string, recursive bool) ([]map[string]*ast.Package, error) {
 	var pkgs []map[string]*ast.Package
-	fset := token.NewFileSet()
 	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
 		if d.IsDir() {
+			if !recursive && path != root {
+				return fs.SkipDir
+			}
+
 			pkg, err := parser.ParseDir(fset, path, func(info fs.FileInfo) bool {
 				return true
 			}, parser.ParseComments)
@@ -88,6 +131,12 @@ func FindAllFunctions(root string) (FunctionList, error) {
 		}
 		return nil
 	})
+	return pkgs, err
+}
+
+func findFunctions(root string, recursive bool) (FunctionList, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parseDirs(fset, root, recursive)
 	if err != nil {
 		return nil, err
 	}
@@ -101,42 +150,10 @@ func FindAllFunctions(root string) (FunctionList, error) {
 			defer wg.Done()
 			for pkgname, pkg := range pkglist {
 				for fpath, file := range pkg.Files {
-					for _, decl := range file.Decls {
-						f, fok := decl.(*ast.FuncDecl)
-						if fok {
-							var recv *Receiver
-							if f.Recv != nil {
-								recv = &Receiver{}
-								def := f.Recv.List[0]
-								if t, isPointer := def.Type.(*ast.StarExpr); isPointer {
-									recv.Pointer = true
-									recv.ReceiverType = t.X.(*ast.Ident).Name
-								} else {
-									recv.ReceiverType = def.Type.(*ast.Ident).Name
-								}
-							}
-
-							docs := strings.Split(f.Doc.Text(), "\n")
-							var lines []string
-							for _, dl := range docs {
-								if t := strings.TrimSpace(dl); t != "" {
-									lines = append(lines, t)
-								}
-							}
-
-							doc := Function{
-								Comments: lines,
-								FilePath: fpath,
-								Name:     f.Name.String(),
-								Package:  pkgname,
-								Receiver: recv,
-							}
-
-							lock.Lock()
-							results = append(results, doc)
-							lock.Unlock()
-						}
-					}
+					specs := fileFunctions(fset, pkgname, fpath, file)
+					lock.Lock()
+					results = append(results, specs...)
+					lock.Unlock()
 				}
 			}
 		}(p)
@@ -146,17 +163,43 @@ func FindAllFunctions(root string) (FunctionList, error) {
 }
 
 type Type struct {
-	Comments []string `json:"-"`
-	FilePath string   `json:"file_path"`
-	Name     string   `json:"name"`
-	Package  string   `json:"package"`
-	Fields   []Field  `json:"fields"`
+	Comments         []string `json:"-"`
+	CommentLines     []int    `json:"-"`
+	LineComments     []string `json:"-"`
+	LineCommentLines []int    `json:"-"`
+	Directives       []string `json:"-"`
+	FileDirectives   []string `json:"-"`
+	FilePath         string   `json:"file_path"`
+	Line             int      `json:"line"`
+	Name             string   `json:"name"`
+	Package          string   `json:"package"`
+	Fields           []Field  `json:"fields"`
 }
 
 func (t Type) Doc() []string {
 	return t.Comments
 }
 
+func (t Type) LineDoc() []string {
+	return t.LineComments
+}
+
+func (t Type) DirectiveDoc() []string {
+	return t.Directives
+}
+
+func (t Type) FileDirectiveDoc() []string {
+	return t.FileDirectives
+}
+
+func (t Type) Location() (string, int) {
+	return t.FilePath, t.Line
+}
+
+func (t Type) CommentLocations() ([]int, []int) {
+	return t.CommentLines, t.LineCommentLines
+}
+
 type FieldType struct {
 	Package string `json:"package,omitempty"`
 	Name    string `json:"name"`
@@ -183,16 +226,38 @@ func (ft FieldType) String() string {
 }
 
 type Field struct {
-	Comments []string          `json:"-"`
-	Name     string            `json:"name"`
-	Type     FieldType         `json:"type"`
-	Tags     map[string]string `json:"tags"`
+	Comments         []string          `json:"-"`
+	CommentLines     []int             `json:"-"`
+	LineComments     []string          `json:"-"`
+	LineCommentLines []int             `json:"-"`
+	Directives       []string          `json:"-"`
+	FilePath         string            `json:"-"`
+	Line             int               `json:"line"`
+	Name             string            `json:"name"`
+	Type             FieldType         `json:"type"`
+	Tags             map[string]string `json:"tags"`
 }
 
 func (f Field) Doc() []string {
 	return f.Comments
 }
 
+func (f Field) LineDoc() []string {
+	return f.LineComments
+}
+
+func (f Field) DirectiveDoc() []string {
+	return f.Directives
+}
+
+func (f Field) Location() (string, int) {
+	return f.FilePath, f.Line
+}
+
+func (f Field) CommentLocations() ([]int, []int) {
+	return f.CommentLines, f.LineCommentLines
+}
+
 type TypeList []Type
 
 // Find searches a type with name and returns a pointer or nil
@@ -212,20 +277,70 @@ func (tl TypeList) Find(name string) *Type {
 // To be used to use go code as metaprogramming input for code generation and similar
 // functions
 func FindAllTypes(root string) (TypeList, error) {
-	var pkgs []map[string]*ast.Package
-	fset := token.NewFileSet()
-	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
-		if d.IsDir() {
-			pkg, err := parser.ParseDir(fset, path, func(info fs.FileInfo) bool {
-				return true
-			}, parser.ParseComments)
-			if err != nil {
-				return err
+	return findTypes(root, true)
+}
+
+// FindPackageTypes works like FindAllTypes, but only extracts the types of the package in dir, without traversing
+// sub directories
+func FindPackageTypes(dir string) (TypeList, error) {
+	return findTypes(dir, false)
+}
+
+// FindFileTypes extracts all types of a file already parsed (with comments) into fset, e.g. by go/analysis
+func FindFileTypes(fset *token.FileSet, file *ast.File) TypeList {
+	return fileTypes(fset, file.Name.Name, fset.Position(file.Pos()).Filename, file)
+}
+
+// FindFileFunctions extracts all functions of a file already parsed (with comments) into fset, e.g. by go/analysis
+func FindFileFunctions(fset *token.FileSet, file *ast.File) FunctionList {
+	return fileFunctions(fset, file.Name.Name, fset.Position(file.Pos()).Filename, file)
+}
+
+// fileFunctions extracts all functions of a parsed file
+func fileFunctions(fset *token.FileSet, pkgname, fpath string, file *ast.File) []Function {
+	var results []Function
+	fileDirectives := fileDirectiveLines(file)
+	for _, decl := range file.Decls {
+		f, fok := decl.(*ast.FuncDecl)
+		if fok {
+			var recv *Receiver
+			if f.Recv != nil {
+				recv = &Receiver{}
+				def := f.Recv.List[0]
+				if t, isPointer := def.Type.(*ast.StarExpr); isPointer {
+					recv.Pointer = true
+					recv.ReceiverType = typeName(t.X)
+				} else {
+					recv.ReceiverType = typeName(def.Type)
+				}
 			}
-			pkgs = append(pkgs, pkg)
+
+			trailing := trailingComment(fset, file, f)
+			lines, lineNumbers := commentLines(fset, f.Doc)
+			lineComments, lineCommentNumbers := commentLines(fset, trailing)
+			doc := Function{
+				Comments:         lines,
+				CommentLines:     lineNumbers,
+				LineComments:     lineComments,
+				LineCommentLines: lineCommentNumbers,
+				Directives:       directiveLines(f.Doc, trailing),
+				FileDirectives:   fileDirectives,
+				FilePath:         fpath,
+				Line:             fset.Position(f.Pos()).Line,
+				Name:             f.Name.String(),
+				Package:          pkgname,
+				Receiver:         recv,
+			}
+
+			results = append(results, doc)
 		}
-		return nil
-	})
+	}
+	return results
+}
+
+func findTypes(root string, recursive bool) (TypeList, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parseDirs(fset, root, recursive)
 	if err != nil {
 		return nil, err
 	}
@@ -239,148 +354,323 @@ func FindAllTypes(root string) (TypeList, error) {
 			defer wg.Done()
 			for pkgname, pkg := range pkglist {
 				for fpath, file := range pkg.Files {
-					for _, decl := range file.Decls {
-						g, gok := decl.(*ast.GenDecl)
-						if gok {
-							docs := strings.Split(g.Doc.Text(), "\n")
-							var lines []string
-							for _, dl := range docs {
-								if t := strings.TrimSpace(dl); t != "" {
-									lines = append(lines, t)
+					specs := fileTypes(fset, pkgname, fpath, file)
+					lock.Lock()
+					results = append(results, specs...)
+					lock.Unlock()
+				}
+			}
+		}(p)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// commentLines returns the trimmed, non-empty lines of a comment group alongside their line numbers; like the
+// comment group text, comment markers and directives are omitted
+func commentLines(fset *token.FileSet, cg *ast.CommentGroup) ([]string, []int) {
+	if cg == nil {
+		return nil, nil
+	}
+
+	var lines []string
+	var numbers []int
+	for _, c := range cg.List {
+		line := fset.Position(c.Pos()).Line
+
+		var text []string
+		switch c.Text[1] {
+		case '/':
+			if IsDirective(c.Text) {
+				continue
+			}
+			text = []string{c.Text[2:]}
+		case '*':
+			text = strings.Split(c.Text[2:len(c.Text)-2], "\n")
+		}
+
+		for idx, l := range text {
+			if t := strings.TrimSpace(l); t != "" {
+				lines = append(lines, t)
+				numbers = append(numbers, line+idx)
+			}
+		}
+	}
+	return lines, numbers
+}
+
+// directiveLines returns the directive comments (e.g. `//go:generate ...`, `//nolint`) of the comment groups without
+// the leading slashes; those are omitted by the comment group text
+func directiveLines(cgs ...*ast.CommentGroup) []string {
+	var lines []string
+	for _, cg := range cgs {
+		if cg == nil {
+			continue
+		}
+
+		for _, c := range cg.List {
+			if IsDirective(c.Text) {
+				lines = append(lines, strings.TrimSpace(c.Text[2:]))
+			}
+		}
+	}
+	return lines
+}
+
+// fileDirectiveLines returns the directive comments above the package clause of a file without the leading slashes,
+// e.g. the build constraints `go:build linux` and, in their legacy form, `+build linux`
+func fileDirectiveLines(file *ast.File) []string {
+	var lines []string
+	for _, cg := range file.Comments {
+		if cg.Pos() >= file.Package {
+			break
+		}
+
+		for _, c := range cg.List {
+			if IsDirective(c.Text) || strings.HasPrefix(c.Text, "// +build ") {
+				lines = append(lines, strings.TrimSpace(c.Text[2:]))
+			}
+		}
+	}
+	return lines
+}
+
+// IsDirective checks if a comment (including its slashes) is a directive, following the go/ast convention of `//line`,
+// `//extern`, `//export` and `//[a-z0-9]+:[a-z0-9]`; additionally `//nolint` is considered a directive
+func IsDirective(c string) bool {
+	if !strings.HasPrefix(c, "//") {
+		return false
+	}
+	c = c[2:]
+
+	for _, p := range []string{"line ", "extern ", "export "} {
+		if strings.HasPrefix(c, p) {
+			return true
+		}
+	}
+
+	if c == "nolint" || strings.HasPrefix(c, "nolint:") || strings.HasPrefix(c, "nolint ") {
+		return true
+	}
+
+	return directiveRe.MatchString(c)
+}
+
+// trailingComment returns the comment group following a function declaration on the same line as its end (e.g.
+// `func A() {} // comment`), or nil if there is none
+func trailingComment(fset *token.FileSet, file *ast.File, f *ast.FuncDecl) *ast.CommentGroup {
+	end := fset.Position(f.End())
+	for _, cg := range file.Comments {
+		if cg.Pos() < f.End() {
+			continue
+		}
+
+		if fset.Position(cg.Pos()).Line == end.Line {
+			return cg
+		}
+		break
+	}
+	return nil
+}
+
+func predeclaredName(n string) bool {
+	for _, t := range gotypes {
+		if n == t {
+			return true
+		}
+	}
+	return false
+}
+
+// fileTypes extracts all types of a parsed file
+func fileTypes(fset *token.FileSet, pkgname, fpath string, file *ast.File) []Type {
+	var results []Type
+	fileDirectives := fileDirectiveLines(file)
+	for _, decl := range file.Decls {
+		g, gok := decl.(*ast.GenDecl)
+		if gok {
+			lines, lineNumbers := commentLines(fset, g.Doc)
+
+			for _, s := range g.Specs {
+				t, tok := s.(*ast.TypeSpec)
+				if tok {
+					var fields []Field
+					s, sok := t.Type.(*ast.StructType)
+					if sok {
+						if s.Fields != nil {
+							for _, f := range s.Fields.List {
+								newField := func(ft FieldType, tags map[string]string) Field {
+									// embedded fields are named after their type
+									name := ft.Name
+									if len(f.Names) > 0 {
+										name = f.Names[0].String()
+									}
+
+									lines, lineNumbers := commentLines(fset, f.Doc)
+									lineComments, lineCommentNumbers := commentLines(fset, f.Comment)
+									return Field{
+										Comments:         lines,
+										CommentLines:     lineNumbers,
+										LineComments:     lineComments,
+										LineCommentLines: lineCommentNumbers,
+										Directives:       directiveLines(f.Doc, f.Comment),
+										FilePath:         fpath,
+										Line:             fset.Position(f.Pos()).Line,
+										Name:             name,
+										Type:             ft,
+										Tags:             tags,
+									}
 								}
-							}
 
-							for _, s := range g.Specs {
-								t, tok := s.(*ast.TypeSpec)
-								if tok {
-									var fields []Field
-									s, sok := t.Type.(*ast.StructType)
-									if sok {
-										if s.Fields != nil {
-											for _, f := range s.Fields.List {
-												docs := strings.Split(f.Doc.Text(), "\n")
-												var lines []string
-												for _, dl := range docs {
-													if t := strings.TrimSpace(dl); t != "" {
-														lines = append(lines, t)
-													}
-												}
-
-												var tags map[string]string
-												if f.Tag != nil {
-													tags = map[string]string{}
-													pairs := strings.Split(f.Tag.Value[1:len(f.Tag.Value)-1], ",")
-													for _, p := range pairs {
-														kv := strings.Split(p, ":")
-														tags[kv[0]] = kv[1]
-													}
-												}
-
-												st, stok := f.Type.(*ast.Ident)
-												if stok {
-													impliedPkg := ""
-													if !predeclaredName(st.Name) {
-														impliedPkg = pkgname
-													}
-
-													ft := FieldType{
-														Package:            impliedPkg,
-														Name:               st.Name,
-														PackageNameImplied: impliedPkg != "",
-													}
-
-													fields = append(fields, Field{
-														Comments: lines,
-														Name:     f.Names[0].String(),
-														Type:     ft,
-														Tags:     tags,
-													})
-												}
-
-												set, setok := f.Type.(*ast.SelectorExpr)
-												if setok {
-													fields = append(fields, Field{
-														Comments: lines,
-														Name:     f.Names[0].String(),
-														Type: FieldType{
-															Package: set.X.(*ast.Ident).Name,
-															Name:    set.Sel.Name,
-														},
-														Tags: tags,
-													})
-												}
-
-												stet, stetok := f.Type.(*ast.StarExpr)
-												if stetok {
-													// type pointer
-													tp, tpok := stet.X.(*ast.SelectorExpr)
-													if tpok {
-														fields = append(fields, Field{
-															Comments: lines,
-															Name:     f.Names[0].String(),
-															Type: FieldType{
-																Package: tp.X.(*ast.Ident).Name,
-																Name:    tp.Sel.Name,
-																Pointer: true,
-															},
-															Tags: tags,
-														})
-													}
-
-													// scalar pointer
-													sp, spok := stet.X.(*ast.Ident)
-													if spok {
-														impliedPkg := ""
-														if !predeclaredName(sp.Name) {
-															impliedPkg = pkgname
-														}
-
-														ft := FieldType{
-															Package:            impliedPkg,
-															Name:               sp.Name,
-															Pointer:            true,
-															PackageNameImplied: impliedPkg != "",
-														}
-
-														fields = append(fields, Field{
-															Comments: lines,
-															Name:     f.Names[0].String(),
-															Type:     ft,
-															Tags:     tags,
-														})
-													}
-												}
-											}
-										}
+								var tags map[string]string
+								if f.Tag != nil {
+									tags = structTags(f.Tag.Value)
+								}
+
+								st, stok := f.Type.(*ast.Ident)
+								if stok {
+									impliedPkg := ""
+									if !predeclaredName(st.Name) {
+										impliedPkg = pkgname
 									}
 
-									doc := Type{
-										Comments: lines,
-										FilePath: fpath,
-										Name:     t.Name.String(),
-										Package:  pkgname,
-										Fields:   fields,
+									ft := FieldType{
+										Package:            impliedPkg,
+										Name:               st.Name,
+										PackageNameImplied: impliedPkg != "",
+									}
+
+									fields = append(fields, newField(ft, tags))
+								}
+
+								set, setok := f.Type.(*ast.SelectorExpr)
+								if setok {
+									fields = append(fields, newField(FieldType{
+										Package: set.X.(*ast.Ident).Name,
+										Name:    set.Sel.Name,
+									}, tags))
+								}
+
+								stet, stetok := f.Type.(*ast.StarExpr)
+								if stetok {
+									// type pointer
+									tp, tpok := stet.X.(*ast.SelectorExpr)
+									if tpok {
+										fields = append(fields, newField(FieldType{
+											Package: tp.X.(*ast.Ident).Name,
+											Name:    tp.Sel.Name,
+											Pointer: true,
+										}, tags))
+									}
+
+									// scalar pointer
+									sp, spok := stet.X.(*ast.Ident)
+									if spok {
+										impliedPkg := ""
+										if !predeclaredName(sp.Name) {
+											impliedPkg = pkgname
+										}
+
+										ft := FieldType{
+											Package:            impliedPkg,
+											Name:               sp.Name,
+											Pointer:            true,
+											PackageNameImplied: impliedPkg != "",
+										}
+
+										fields = append(fields, newField(ft, tags))
 									}
-									lock.Lock()
-									results = append(results, doc)
-									lock.Unlock()
 								}
 							}
 						}
 					}
+
+					lineComments, lineCommentNumbers := commentLines(fset, t.Comment)
+					doc := Type{
+						Comments:         lines,
+						CommentLines:     lineNumbers,
+						LineComments:     lineComments,
+						LineCommentLines: lineCommentNumbers,
+						Directives:       directiveLines(g.Doc, t.Comment),
+						FileDirectives:   fileDirectives,
+						FilePath:         fpath,
+						Line:             fset.Position(t.Pos()).Line,
+						Name:             t.Name.String(),
+						Package:          pkgname,
+						Fields:           fields,
+					}
+					results = append(results, doc)
 				}
 			}
-		}(p)
+		}
 	}
-	wg.Wait()
-	return results, nil
+	return results
 }
 
-func predeclaredName(n string) bool {
-	for _, t := range gotypes {
-		if n == t {
-			return true
+// typeName returns the name of a (receiver) type expression, without type parameters (e.g. `List` of `List[T]`); it is
+// empty for expressions which are no named type
+func typeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.IndexExpr:
+		return typeName(t.X)
+	case *ast.IndexListExpr:
+		return typeName(t.X)
+	case *ast.ParenExpr:
+		return typeName(t.X)
+	}
+	return ""
+}
+
+// structTags parses the struct tag literal into its values by key, following the conventions of reflect.StructTag
+// (e.g. `json:"id,omitempty" db:"id"`); parsing stops at the first malformed pair. Tags not following the conventions
+// are read in the unquoted form `key:value,key:value`
+func structTags(literal string) map[string]string {
+	tags := map[string]string{}
+	tag, err := strconv.Unquote(literal)
+	if err != nil {
+		return tags
+	}
+
+	if !strings.Contains(tag, `:"`) {
+		for _, p := range strings.Split(tag, ",") {
+			if k, v, ok := strings.Cut(p, ":"); ok {
+				tags[k] = v
+			}
 		}
+		return tags
 	}
-	return false
+
+	for tag != "" {
+		tag = strings.TrimLeft(tag, " ")
+		idx := 0
+		for idx < len(tag) && tag[idx] > ' ' && tag[idx] != ':' && tag[idx] != '"' && tag[idx] != 0x7f {
+			idx++
+		}
+		if idx == 0 || idx+1 >= len(tag) || tag[idx] != ':' || tag[idx+1] != '"' {
+			break
+		}
+		key := tag[:idx]
+		tag = tag[idx+1:]
+
+		// the value is a quoted string, which may contain escaped quotes
+		idx = 1
+		for idx < len(tag) && tag[idx] != '"' {
+			if tag[idx] == '\\' {
+				idx++
+			}
+			idx++
+		}
+		if idx >= len(tag) {
+			break
+		}
+		value, err := strconv.Unquote(tag[:idx+1])
+		if err != nil {
+			break
+		}
+		tags[key] = value
+		tag = tag[idx+1:]
+	}
+	return tags
 }
@@ -2,6 +2,8 @@ package inspect
 
 import (
 	"fmt"
+	"go/parser"
+	"go/token"
 	"strings"
 	"testing"
 )
@@ -97,6 +99,14 @@ func Test_FindAllFunctions(t *testing.T) {
 			t.Error("TestA failed expectation")
 		}
 
+		if strings.Join(ta.Directives, "") != "go:generate echo TestA" {
+			t.Error("TestA failed directive expectation")
+		}
+
+		if ta.Line != 12 || fmt.Sprint(ta.CommentLines) != "[7 8 9]" {
+			t.Errorf("TestA failed line expectation (line=%v, comment lines=%v)", ta.Line, ta.CommentLines)
+		}
+
 		aa := funcs.Find("AA")
 		if aa == nil {
 			t.Error("failed to find 'AA'")
@@ -112,6 +122,10 @@ func Test_FindAllFunctions(t *testing.T) {
 			t.Error("AA failed expectation")
 		}
 
+		if strings.Join(aa.LineComments, "") != "trailing comment on AA" || fmt.Sprint(aa.LineCommentLines) != "[27]" {
+			t.Error("AA failed line comment expectation")
+		}
+
 		ab := funcs.Find("AB")
 		if ab == nil {
 			t.Error("failed to find 'AB'")
@@ -127,6 +141,10 @@ func Test_FindAllFunctions(t *testing.T) {
 			t.Error("AB failed expectation")
 		}
 
+		if ab.LineComments != nil {
+			t.Error("AB failed line comment expectation")
+		}
+
 		cf := funcs.Find("ComplicatedFunction")
 		if cf == nil {
 			t.Error("failed to find 'ComplicatedFunction'")
@@ -291,6 +309,31 @@ func Test_FindAllTypes(t *testing.T) {
 			)
 			t.Error("TestTypeA failed expectation")
 		}
+
+		if types[1].Fields[1].LineComments != nil ||
+			strings.Join(types[1].Fields[1].Directives, "") != "nolint:unused" ||
+			types[1].Fields[0].Directives != nil {
+			t.Error("TestTypeA failed field directive expectation")
+		}
+
+		if types[1].Fields[0].LineComments != nil ||
+			strings.Join(types[1].Fields[5].LineComments, "") != "trailing comment on ValueB" {
+			t.Error("TestTypeA failed field line comment expectation")
+		}
+
+		if types[1].Line != 15 ||
+			fmt.Sprint(types[1].CommentLines) != "[14]" ||
+			types[1].Fields[0].Line != 17 ||
+			types[1].Fields[0].FilePath != "internal/success/a.go" ||
+			fmt.Sprint(types[1].Fields[0].CommentLines) != "[16]" ||
+			fmt.Sprint(types[1].Fields[5].LineCommentLines) != "[22]" {
+			t.Error("TestTypeA failed line expectation")
+		}
+
+		lt := types.Find("LocalType")
+		if lt == nil || strings.Join(lt.LineComments, "") != "trailing comment on LocalType" {
+			t.Error("LocalType failed line comment expectation")
+		}
 	})
 
 	t.Run("error", func(t *testing.T) {
@@ -305,6 +348,122 @@ func Test_FindAllTypes(t *testing.T) {
 	})
 }
 
+func Test_FindPackage(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		funcs, err := FindPackageFunctions("./internal/success")
+		if err != nil {
+			t.Fatal(err)
+		}
+		types, err := FindPackageTypes("./internal/success")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(funcs) != 4 || len(types) != 5 {
+			t.Errorf("unexpected specs (funcs=%v, types=%v)", len(funcs), len(types))
+		}
+		for _, f := range funcs {
+			if f.Package != "success" {
+				t.Errorf("expected no function of sub directory, got %v", f.Name)
+			}
+		}
+	})
+
+	t.Run("sub directories are skipped", func(t *testing.T) {
+		funcs, err := FindPackageFunctions("./internal")
+		if err != nil || len(funcs) != 0 {
+			t.Errorf("expected no functions and no error (funcs=%v, err=%v)", len(funcs), err)
+		}
+
+		types, err := FindPackageTypes("./internal")
+		if err != nil || len(types) != 0 {
+			t.Errorf("expected no types and no error (types=%v, err=%v)", len(types), err)
+		}
+	})
+}
+
+func Test_FindFile(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "./internal/success/a.go", nil, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	funcs := FindFileFunctions(fset, f)
+	types := FindFileTypes(fset, f)
+	if len(funcs) != 4 || len(types) != 5 {
+		t.Errorf("unexpected specs (funcs=%v, types=%v)", len(funcs), len(types))
+	}
+
+	ta := funcs.Find("TestA")
+	if ta == nil || ta.Package != "success" || ta.FilePath != "./internal/success/a.go" || ta.Line != 12 {
+		t.Errorf("unexpected function %v", ta)
+	}
+
+	// build constraints are above the package clause, so they belong to the file
+	if strings.Join(ta.FileDirectives, "|") != "go:build exclude" || strings.Join(types[0].FileDirectives, "|") != "go:build exclude" {
+		t.Errorf("unexpected file directives %v, %v", ta.FileDirectives, types[0].FileDirectives)
+	}
+}
+
+func Test_FindFileLegacyBuildConstraint(t *testing.T) {
+	src := "// +build linux\n\n// Package a is documented\npackage a\n\n//go:noinline\nfunc A() {}\n"
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "a.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := FindFileFunctions(fset, f).Find("A")
+	if a == nil || strings.Join(a.FileDirectives, "|") != "+build linux" || strings.Join(a.Directives, "|") != "go:noinline" {
+		t.Errorf("unexpected function %v", a)
+	}
+}
+
+func Test_FindFileEmbeddedAndGeneric(t *testing.T) {
+	src := `package model
+
+type Base struct{}
+
+type User struct {
+	Base
+	*Audit
+	ID   string ` + "`json:\"identifier,omitempty\" db:\"id\"`" + `
+	Name string ` + "`json:\"name\"`" + `
+}
+
+type List[T any] struct{}
+
+func (l *List[T]) Len() int { return 0 }
+
+func (m Map[K, V]) Keys() []K { return nil }
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "model.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := FindFileTypes(fset, f).Find("User")
+	if user == nil || len(user.Fields) != 4 {
+		t.Fatalf("unexpected type %v", user)
+	}
+	if user.Fields[0].Name != "Base" || user.Fields[1].Name != "Audit" || !user.Fields[1].Type.Pointer {
+		t.Errorf("unexpected embedded fields %v, %v", user.Fields[0], user.Fields[1])
+	}
+	if tags := user.Fields[2].Tags; tags["json"] != "identifier,omitempty" || tags["db"] != "id" {
+		t.Errorf("unexpected tags %v", tags)
+	}
+
+	funcs := FindFileFunctions(fset, f)
+	if l := funcs.Find("Len"); l == nil || l.Receiver.ReceiverType != "List" || !l.Receiver.Pointer {
+		t.Errorf("unexpected function %v", l)
+	}
+	if k := funcs.Find("Keys"); k == nil || k.Receiver.ReceiverType != "Map" {
+		t.Errorf("unexpected function %v", k)
+	}
+}
+
 func Test_FieldDoc(t *testing.T) {
 	comments := []string{
 		"Test",
@@ -319,6 +478,40 @@ func Test_FieldDoc(t *testing.T) {
 	}
 }
 
+func Test_LineDoc(t *testing.T) {
+	comments := []string{
+		"Test",
+	}
+
+	if strings.Join(comments, "") != strings.Join(Function{LineComments: comments}.LineDoc(), "") ||
+		strings.Join(comments, "") != strings.Join(Type{LineComments: comments}.LineDoc(), "") ||
+		strings.Join(comments, "") != strings.Join(Field{LineComments: comments}.LineDoc(), "") {
+		t.Error("expected line document returned be same as line comments")
+	}
+}
+
+func Test_IsDirective(t *testing.T) {
+	for c, directive := range map[string]bool{
+		"//go:generate stringer":     true,
+		"//go:embed file.txt":        true,
+		"//lint:ignore U1000":        true,
+		"//nolint":                   true,
+		"//nolint:errcheck":          true,
+		"//nolint:":                  true,
+		"//nolint errcheck":          true,
+		"//line a.go:1":              true,
+		"//export Name":              true,
+		"// go:generate stringer":    false,
+		"//Go:generate":              false,
+		"// crud.field{name=\"id\"}": false,
+		"/* go:generate */":          false,
+	} {
+		if IsDirective(c) != directive {
+			t.Errorf("expected '%v' directive to be %v", c, directive)
+		}
+	}
+}
+
 func Test_TypeDoc(t *testing.T) {
 	comments := []string{
 		"Test",
@@ -7,22 +7,24 @@ import "bytes"
 // Comments
 // another line
 // and another
+//
+//go:generate echo TestA
 func TestA() {}
 
 // Test comment on TestTypeA
 type TestTypeA struct {
 	// Test comment on ValueA
-	ValueA             string `literal:tag,json:something`
-	ComplexType        bytes.Buffer
+	ValueA             string       `literal:tag,json:something`
+	ComplexType        bytes.Buffer //nolint:unused
 	ComplexTypePointer *bytes.Buffer
 	StringPointer      *LocalType
 	String             LocalType
-	ValueB             *string
+	ValueB             *string // trailing comment on ValueB
 }
 
-type LocalType string
+type LocalType string // trailing comment on LocalType
 
-func (ttb TestTypeA) AA()  {}
+func (ttb TestTypeA) AA()  {} // trailing comment on AA
 func (ttb *TestTypeA) AB() {}
 
 func ComplicatedFunction() {
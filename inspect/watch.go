@@ -0,0 +1,239 @@
+package inspect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultWatchInterval is the polling interval of a Watcher, if none is set
+const DefaultWatchInterval = 500 * time.Millisecond
+
+// Change is the state of the inspection after go files below the root of a Watcher changed
+type Change struct {
+	// Dirs are the directories which were re-inspected (all on the first change)
+	Dirs []string
+
+	Types     TypeList
+	Functions FunctionList
+
+	// Err holds the errors of directories which failed to be inspected (e.g. due to syntax errors while editing);
+	// their last successful inspection is kept in Types and Functions
+	Err error
+}
+
+// fileState identifies the content of a file
+type fileState struct {
+	modTime time.Time
+	size    int64
+	hash    uint64
+}
+
+// Watcher polls the go files below a root and re-inspects the packages of the directories in which files were
+// created, changed or removed; files rewritten with the same content are not considered changed
+type Watcher struct {
+	Root string
+
+	// Interval is the polling interval, defaults to DefaultWatchInterval
+	Interval time.Duration
+
+	// Debounce is the time without further changes to wait for before re-inspecting, so that a burst of changes
+	// (e.g. a checkout or formatting on save) is inspected at once; defaults to the interval
+	Debounce time.Duration
+
+	files map[string]fileState
+	types map[string]TypeList
+	funcs map[string]FunctionList
+}
+
+// Watch inspects all packages below the root and calls fn, afterwards it calls fn whenever packages changed until the
+// context is done; only a failure of the first scan ends the watch, later scan errors are logged and retried on the
+// next poll
+func (w *Watcher) Watch(ctx context.Context, fn func(Change)) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	debounce := w.Debounce
+	if debounce <= 0 {
+		debounce = interval
+	}
+
+	changed, err := w.scan()
+	if err != nil {
+		return err
+	}
+	fn(w.inspect(changed))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	pending := map[string]bool{}
+	var last time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			changed, err := w.scan()
+			if err != nil {
+				slog.Error("failed to scan", "root", w.Root, "err", err)
+				continue
+			}
+
+			if len(changed) > 0 {
+				for _, dir := range changed {
+					pending[dir] = true
+				}
+				last = now
+				continue
+			}
+
+			if len(pending) == 0 || now.Sub(last) < debounce {
+				continue
+			}
+
+			dirs := make([]string, 0, len(pending))
+			for dir := range pending {
+				dirs = append(dirs, dir)
+			}
+			sort.Strings(dirs)
+			pending = map[string]bool{}
+
+			fn(w.inspect(dirs))
+		}
+	}
+}
+
+// scan compares the go files below the root against the last scan and returns the directories with changes; files and
+// directories removed while scanning (e.g. by an editor saving through a temporary file) are skipped
+func (w *Watcher) scan() ([]string, error) {
+	files := map[string]fileState{}
+	dirs := map[string]bool{}
+	err := filepath.WalkDir(w.Root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path != w.Root {
+				return nil
+			}
+			return err
+		}
+
+		if d.IsDir() {
+			dirs[path] = true
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		state := fileState{modTime: info.ModTime(), size: info.Size()}
+		if last, ok := w.files[path]; ok && last.modTime.Equal(state.modTime) && last.size == state.size {
+			files[path] = last
+			return nil
+		}
+
+		state.hash, err = hashFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		files[path] = state
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	changed := map[string]bool{}
+	for path, state := range files {
+		if last, ok := w.files[path]; !ok || last.hash != state.hash {
+			changed[filepath.Dir(path)] = true
+		}
+	}
+	for path := range w.files {
+		if _, ok := files[path]; !ok {
+			changed[filepath.Dir(path)] = true
+		}
+	}
+	if w.files == nil {
+		changed = dirs // everything is new on the first scan, including directories without go files
+	}
+	w.files = files
+
+	var result []string
+	for dir := range changed {
+		result = append(result, dir)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// inspect re-inspects the packages of the directories and returns the state of all packages
+func (w *Watcher) inspect(dirs []string) Change {
+	if w.types == nil {
+		w.types, w.funcs = map[string]TypeList{}, map[string]FunctionList{}
+	}
+
+	var errs []error
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+			delete(w.types, dir)
+			delete(w.funcs, dir)
+			continue
+		}
+
+		types, err := FindPackageTypes(dir)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%v: %w", dir, err))
+			continue
+		}
+		funcs, err := FindPackageFunctions(dir)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%v: %w", dir, err))
+			continue
+		}
+		w.types[dir], w.funcs[dir] = types, funcs
+	}
+
+	all := make([]string, 0, len(w.types))
+	for dir := range w.types {
+		all = append(all, dir)
+	}
+	sort.Strings(all)
+
+	c := Change{Dirs: dirs, Err: errors.Join(errs...)}
+	for _, dir := range all {
+		c.Types = append(c.Types, w.types[dir]...)
+		c.Functions = append(c.Functions, w.funcs[dir]...)
+	}
+	return c
+}
+
+func hashFile(path string) (uint64, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	h := fnv.New64a()
+	h.Write(b)
+	return h.Sum64(), nil
+}
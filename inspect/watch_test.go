@@ -0,0 +1,126 @@
+package inspect
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_WatcherScan(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "sub")
+	writeFile(t, filepath.Join(root, "a.go"), "package a\n\ntype A struct{}\n")
+	writeFile(t, filepath.Join(sub, "b.go"), "package sub\n\nfunc B() {}\n")
+
+	w := &Watcher{Root: root}
+	changed, err := w.scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 2 {
+		t.Fatalf("expected all directories on first scan, got %v", changed)
+	}
+
+	c := w.inspect(changed)
+	if c.Err != nil || len(c.Types) != 1 || len(c.Functions) != 1 {
+		t.Fatalf("unexpected change %v", c)
+	}
+
+	// same content is no change, even if written again
+	writeFile(t, filepath.Join(sub, "b.go"), "package sub\n\nfunc B() {}\n")
+	if changed, _ := w.scan(); len(changed) != 0 {
+		t.Errorf("expected no change, got %v", changed)
+	}
+
+	writeFile(t, filepath.Join(sub, "b.go"), "package sub\n\nfunc B() {}\n\nfunc C() {}\n")
+	changed, _ = w.scan()
+	if len(changed) != 1 || changed[0] != sub {
+		t.Fatalf("expected sub to be changed, got %v", changed)
+	}
+	if c := w.inspect(changed); len(c.Types) != 1 || len(c.Functions) != 2 {
+		t.Errorf("expected unchanged packages to be kept, got %v", c)
+	}
+
+	writeFile(t, filepath.Join(sub, "b.go"), "package sub\n\nfunc B( {}\n")
+	changed, _ = w.scan()
+	if c := w.inspect(changed); c.Err == nil || len(c.Functions) != 2 {
+		t.Errorf("expected error and last inspection to be kept, got %v", c)
+	}
+
+	if err := os.RemoveAll(sub); err != nil {
+		t.Fatal(err)
+	}
+	changed, _ = w.scan()
+	if c := w.inspect(changed); c.Err != nil || len(c.Functions) != 0 {
+		t.Errorf("expected removed package to be dropped, got %v", c)
+	}
+}
+
+func Test_WatcherScanVanished(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "a.go"), "package a\n")
+	// a file removed between listing and reading is skipped
+	if err := os.Symlink(filepath.Join(root, "gone.go"), filepath.Join(root, "b.go")); err != nil {
+		t.Skip(err)
+	}
+
+	w := &Watcher{Root: root}
+	if _, err := w.scan(); err != nil {
+		t.Errorf("expected vanished file to be skipped, got %v", err)
+	}
+
+	w = &Watcher{Root: filepath.Join(root, "missing")}
+	if _, err := w.scan(); err == nil {
+		t.Error("expected missing root to fail")
+	}
+}
+
+func Test_WatcherWatch(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "a.go"), "package a\n\ntype A struct{}\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan Change)
+	done := make(chan error)
+	w := &Watcher{Root: root, Interval: 10 * time.Millisecond, Debounce: 30 * time.Millisecond}
+	go func() {
+		done <- w.Watch(ctx, func(c Change) {
+			changes <- c
+		})
+	}()
+
+	if c := <-changes; len(c.Types) != 1 {
+		t.Fatalf("expected initial inspection, got %v", c)
+	}
+
+	writeFile(t, filepath.Join(root, "b.go"), "package a\n\ntype B struct{}\n")
+	writeFile(t, filepath.Join(root, "c.go"), "package a\n\ntype C struct{}\n")
+
+	select {
+	case c := <-changes:
+		if len(c.Dirs) != 1 || len(c.Types) != 3 {
+			t.Errorf("expected one debounced change, got %v", c)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected change")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
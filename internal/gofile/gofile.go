@@ -0,0 +1,40 @@
+// Package gofile assembles generated Go source files
+package gofile
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// Import is an import of a generated file; Name is the optional alias
+type Import struct {
+	Name string
+	Path string
+}
+
+// Format adds the imports to the source (existing imports are kept, duplicates are merged) and formats it like
+// gofmt; the source must be a complete Go file
+func Format(filename string, src []byte, imports ...Import) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse generated source: %w", err)
+	}
+
+	for _, i := range imports {
+		astutil.AddNamedImport(fset, f, i.Name, i.Path)
+	}
+	ast.SortImports(fset, f)
+
+	var b bytes.Buffer
+	if err := format.Node(&b, fset, f); err != nil {
+		return nil, fmt.Errorf("failed to format generated source: %w", err)
+	}
+	return format.Source(b.Bytes())
+}
@@ -0,0 +1,129 @@
+// Package watch reads the annotations below a root whenever go files change and passes the result on, as used by the
+// -watch mode of the commands
+package watch
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/troublete/go-annotation/analyze"
+	"github.com/troublete/go-annotation/annotation"
+	"github.com/troublete/go-annotation/inspect"
+)
+
+// Options configure what is watched and how annotations are read
+type Options struct {
+	Root     string
+	Interval time.Duration
+
+	// Schemas is the root path to discover annotation schemas from; the schemas are reloaded if packages below change.
+	// As long as no schema is declared, annotations aren't validated
+	Schemas string
+
+	// Read configures how annotations are read; the registry is set from the schemas
+	Read annotation.Options
+}
+
+// Run reads the annotations below the root and calls emit with the result, afterwards whenever packages changed until
+// the context is done; new and resolved diagnostics are logged as they appear. Emit isn't called if the diagnostics
+// fail the strictness configured
+func Run(ctx context.Context, opts Options, emit func(*annotation.Result) error) error {
+	w := &inspect.Watcher{Root: opts.Root, Interval: opts.Interval}
+	diagnostics := map[diagnosticKey]analyze.Diagnostic{}
+
+	return w.Watch(ctx, func(c inspect.Change) {
+		if c.Err != nil {
+			slog.Error("failed to inspect", "err", c.Err)
+		}
+		slog.Info("inspected", "dirs", c.Dirs)
+
+		if opts.Schemas != "" && (opts.Read.Registry == nil || within(opts.Schemas, c.Dirs)) {
+			r, err := annotation.LoadSchemas(opts.Schemas, opts.Read.Analyzer)
+			if err != nil {
+				slog.Error("failed to load schemas", "err", err)
+				return
+			}
+			opts.Read.Registry = nil
+			if len(r.Schemas()) > len(annotation.MetaSchemas) {
+				opts.Read.Registry = r
+			}
+		}
+
+		read := opts.Read
+		read.Strictness = annotation.NeverFail
+		res, err := annotation.ReadWithOptions(c.Types, c.Functions, read)
+		if err != nil {
+			slog.Error(err.Error())
+			return
+		}
+
+		current := map[diagnosticKey]analyze.Diagnostic{}
+		for _, d := range res.Diagnostics {
+			k := keyOf(d)
+			current[k] = d
+			if _, ok := diagnostics[k]; !ok {
+				LogDiagnostic(d)
+			}
+		}
+		for k, d := range diagnostics {
+			if _, ok := current[k]; !ok {
+				slog.Info("resolved", "code", d.Code, "position", d.Position, "spec", d.Spec)
+			}
+		}
+		diagnostics = current
+
+		if failing := opts.Read.Strictness.Failing(res.Diagnostics); len(failing) > 0 {
+			slog.Error("output not updated", "diagnostics", len(failing), "strict", opts.Read.Strictness)
+			return
+		}
+
+		if err := emit(res); err != nil {
+			slog.Error(err.Error())
+		}
+	})
+}
+
+// diagnosticKey identifies a diagnostic across changes; the position isn't part of it, so diagnostics aren't logged
+// again only because lines above were edited
+type diagnosticKey struct {
+	code, spec, message string
+}
+
+func keyOf(d analyze.Diagnostic) diagnosticKey {
+	return diagnosticKey{code: d.Code, spec: d.Spec, message: d.Message}
+}
+
+// within reports if any of the directories is below root
+func within(root string, dirs []string) bool {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return true
+	}
+
+	for _, dir := range dirs {
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			return true
+		}
+		if rel, err := filepath.Rel(root, dir); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// LogDiagnostic logs a diagnostic with the level matching its severity
+func LogDiagnostic(d analyze.Diagnostic) {
+	level := slog.LevelWarn
+	switch d.Severity {
+	case analyze.SeverityInfo:
+		level = slog.LevelInfo
+	case analyze.SeverityError:
+		level = slog.LevelError
+	}
+
+	slog.Log(context.Background(), level, d.Message, "code", d.Code, "position", d.Position, "spec", d.Spec)
+}
@@ -0,0 +1,182 @@
+package lsp
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/troublete/go-annotation/analyze"
+)
+
+// runeUnits returns the number of UTF-16 code units of a rune
+func runeUnits(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// byteOffset converts a character offset of the protocol (UTF-16 code units) into a byte offset of the line
+func byteOffset(line string, character int) int {
+	units := 0
+	for idx, r := range line {
+		if units >= character {
+			return idx
+		}
+		units += runeUnits(r)
+	}
+	return len(line)
+}
+
+// characterOffset converts a byte offset of the line into a character offset of the protocol (UTF-16 code units)
+func characterOffset(line string, offset int) int {
+	units := 0
+	for idx, r := range line {
+		if idx >= offset {
+			break
+		}
+		units += runeUnits(r)
+	}
+	return units
+}
+
+// commentStart returns the byte offset of the text of a line comment (after `//`, whitespace and the marker, if set)
+func commentStart(line, marker string) (int, bool) {
+	idx := strings.Index(line, "//")
+	if idx < 0 {
+		return 0, false
+	}
+
+	start := idx + 2
+	for start < len(line) && (line[start] == ' ' || line[start] == '\t') {
+		start++
+	}
+
+	if marker != "" {
+		if !strings.HasPrefix(line[start:], marker) {
+			return 0, false
+		}
+		start += len(marker)
+	}
+	return start, true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-'
+}
+
+// completionKind is what can be completed at a position
+type completionKind int
+
+const (
+	completeNothing completionKind = iota
+	completeIdentifier
+	completeKey
+)
+
+// completion is the context of a completion request in an annotation comment
+type completion struct {
+	kind       completionKind
+	identifier string
+	prefix     string
+	present    map[string]bool
+}
+
+// completionAt returns what can be completed at the byte offset of the line
+func completionAt(line string, offset int, marker string) completion {
+	start, ok := commentStart(line, marker)
+	if !ok || offset < start || offset > len(line) {
+		return completion{}
+	}
+
+	before := line[start:offset]
+	brace := strings.Index(before, "{")
+	if brace < 0 {
+		for _, r := range before {
+			if !isWordRune(r) {
+				return completion{}
+			}
+		}
+		return completion{kind: completeIdentifier, prefix: before}
+	}
+
+	inside := before[brace+1:]
+	if strings.Contains(inside, "}") {
+		return completion{}
+	}
+
+	parts, quoted := analyze.SplitAttributes(inside)
+	current := strings.TrimSpace(parts[len(parts)-1])
+	if quoted || strings.Contains(current, "=") {
+		return completion{} // values are not completed
+	}
+
+	present := map[string]bool{}
+	for _, p := range parts[:len(parts)-1] {
+		key, _, _ := strings.Cut(strings.TrimSpace(p), "=")
+		present[key] = true
+	}
+
+	return completion{
+		kind:       completeKey,
+		identifier: strings.TrimSpace(before[:brace]),
+		prefix:     current,
+		present:    present,
+	}
+}
+
+// reference is the annotation identifier or attribute key at a position
+type reference struct {
+	identifier string
+	key        string
+	start, end int
+}
+
+// referenceAt returns the identifier or attribute key at the byte offset of the line
+func referenceAt(line string, offset int, marker string) (reference, bool) {
+	start, ok := commentStart(line, marker)
+	if !ok || offset < start || offset > len(line) {
+		return reference{}, false
+	}
+
+	ws, we := offset, offset
+	for ws > start {
+		r, size := utf8.DecodeLastRuneInString(line[:ws])
+		if !isWordRune(r) {
+			break
+		}
+		ws -= size
+	}
+	for we < len(line) {
+		r, size := utf8.DecodeRuneInString(line[we:])
+		if !isWordRune(r) {
+			break
+		}
+		we += size
+	}
+	if ws == we {
+		return reference{}, false
+	}
+
+	text := line[start:]
+	brace := strings.Index(text, "{")
+	if brace < 0 {
+		return reference{}, false
+	}
+	brace += start
+
+	identifier := strings.TrimSpace(line[start:brace])
+	if we <= brace {
+		return reference{identifier: identifier, start: ws, end: we}, identifier == line[ws:we]
+	}
+
+	// keys follow the opening bracket or a separator and are not part of a quoted value
+	before := strings.TrimSpace(line[brace+1 : ws])
+	if before != "" && !strings.HasSuffix(before, analyze.Separator) {
+		return reference{}, false
+	}
+	if _, quoted := analyze.SplitAttributes(line[brace+1 : ws]); quoted {
+		return reference{}, false
+	}
+	return reference{identifier: identifier, key: line[ws:we], start: ws, end: we}, true
+}
@@ -0,0 +1,84 @@
+package lsp
+
+import "testing"
+
+func Test_Offsets(t *testing.T) {
+	line := `// crud.field{name="ä😀", primary}`
+	for _, tc := range []struct {
+		character, offset int
+	}{
+		{0, 0},
+		{20, 20},
+		{21, 22}, // ä is two bytes, one unit
+		{23, 26}, // 😀 is four bytes, two units
+		{100, len(line)},
+	} {
+		if offset := byteOffset(line, tc.character); offset != tc.offset {
+			t.Errorf("expected byte offset %v for %v, got %v", tc.offset, tc.character, offset)
+		}
+		if tc.character < 100 {
+			if character := characterOffset(line, tc.offset); character != tc.character {
+				t.Errorf("expected character %v for %v, got %v", tc.character, tc.offset, character)
+			}
+		}
+	}
+}
+
+func Test_CompletionAt(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		line   string
+		marker string
+		want   completion
+	}{
+		{"identifier", "// crud.f", "", completion{kind: completeIdentifier, prefix: "crud.f"}},
+		{"empty identifier", "// ", "", completion{kind: completeIdentifier}},
+		{"marker", "// @cr", "@", completion{kind: completeIdentifier, prefix: "cr"}},
+		{"missing marker", "// cr", "@", completion{}},
+		{"prose", "// a comment", "", completion{}},
+		{"first key", "// crud.field{na", "", completion{kind: completeKey, identifier: "crud.field", prefix: "na", present: map[string]bool{}}},
+		{"next key", `// crud.field{name="a, b", `, "", completion{kind: completeKey, identifier: "crud.field", present: map[string]bool{"name": true}}},
+		{"value", `// crud.field{name="`, "", completion{}},
+		{"unquoted value", `// crud.field{max_length=1`, "", completion{}},
+		{"closed", `// crud.field{name="a"} `, "", completion{}},
+		{"no comment", "type User struct", "", completion{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := completionAt(tc.line, len(tc.line), tc.marker)
+			if c.kind != tc.want.kind || c.identifier != tc.want.identifier || c.prefix != tc.want.prefix ||
+				len(c.present) != len(tc.want.present) {
+				t.Fatalf("expected %v, got %v", tc.want, c)
+			}
+			for k := range tc.want.present {
+				if !c.present[k] {
+					t.Errorf("expected '%v' to be present", k)
+				}
+			}
+		})
+	}
+}
+
+func Test_ReferenceAt(t *testing.T) {
+	line := `// crud.field{name="max_length", max_length=10}`
+	for _, tc := range []struct {
+		name   string
+		offset int
+		want   reference
+		ok     bool
+	}{
+		{"identifier", 6, reference{identifier: "crud.field", start: 3, end: 13}, true},
+		{"identifier end", 13, reference{identifier: "crud.field", start: 3, end: 13}, true},
+		{"first key", 15, reference{identifier: "crud.field", key: "name", start: 14, end: 18}, true},
+		{"second key", 35, reference{identifier: "crud.field", key: "max_length", start: 33, end: 43}, true},
+		{"quoted value", 22, reference{}, false},
+		{"value", 44, reference{}, false},
+		{"comment prefix", 1, reference{}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ref, ok := referenceAt(line, tc.offset, "")
+			if ok != tc.ok || (ok && ref != tc.want) {
+				t.Errorf("expected %v (%v), got %v (%v)", tc.want, tc.ok, ref, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,490 @@
+// Package lsp implements a language server for annotations, providing diagnostics, completion of registered
+// identifiers and attribute keys, hover documentation and go-to-definition of schemas
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io"
+	"log/slog"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/troublete/go-annotation/analyze"
+	"github.com/troublete/go-annotation/annotation"
+	"github.com/troublete/go-annotation/inspect"
+)
+
+// Options configure the server
+type Options struct {
+	// Analyzer is used to extract the annotations; if no filter is set, comments not matching the definition form
+	// are skipped
+	Analyzer analyze.Analyzer
+
+	// Schemas is the root path to discover annotation schemas from; defaults to the root of the workspace
+	Schemas string
+}
+
+// Server is a language server for annotations in Go files
+type Server struct {
+	opts     Options
+	conn     *conn
+	root     string
+	registry *analyze.Registry
+	docs     map[string]string
+}
+
+// Serve serves the language server protocol on r and w (e.g. stdin and stdout) until the client exits
+func Serve(r io.Reader, w io.Writer, opts Options) error {
+	if opts.Analyzer.Filter == nil {
+		opts.Analyzer.Filter = analyze.FilterCommentNoAnnotation()
+	}
+
+	s := &Server{
+		opts: opts,
+		conn: newConn(r, w),
+		docs: map[string]string{},
+	}
+
+	for {
+		m, err := s.conn.read()
+		if err != nil {
+			var re *responseError
+			if errors.As(err, &re) {
+				if err := s.conn.reply(nil, nil, re); err != nil {
+					return err
+				}
+				continue
+			}
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		if m.Method == "exit" {
+			return nil
+		}
+
+		result, err := s.handle(m)
+		if m.ID == nil {
+			if err != nil {
+				slog.Error("failed to handle notification", "method", m.Method, "err", err)
+			}
+			continue // notifications are not answered
+		}
+		if err := s.conn.reply(m.ID, result, err); err != nil {
+			return err
+		}
+	}
+}
+
+// handle handles a request or notification; panics are recovered and answered as internal error, so a single request
+// can't take down the server
+func (s *Server) handle(m *message) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("recovered from panic", "method", m.Method, "panic", r)
+			result, err = nil, &responseError{Code: codeInternalError, Message: fmt.Sprintf("panic handling '%v': %v", m.Method, r)}
+		}
+	}()
+
+	switch m.Method {
+	case "initialize":
+		var p initializeParams
+		if err := unmarshalParams(m, &p); err != nil {
+			return nil, err
+		}
+		return s.initialize(p), nil
+	case "shutdown":
+		return nil, nil
+	case "textDocument/didOpen":
+		var p didOpenParams
+		if err := unmarshalParams(m, &p); err != nil {
+			return nil, err
+		}
+		s.docs[p.TextDocument.URI] = p.TextDocument.Text
+		return nil, s.publish(p.TextDocument.URI)
+	case "textDocument/didChange":
+		var p didChangeParams
+		if err := unmarshalParams(m, &p); err != nil {
+			return nil, err
+		}
+		if n := len(p.ContentChanges); n > 0 {
+			s.docs[p.TextDocument.URI] = p.ContentChanges[n-1].Text // full synchronization
+		}
+		return nil, s.publish(p.TextDocument.URI)
+	case "textDocument/didSave":
+		var p didSaveParams
+		if err := unmarshalParams(m, &p); err != nil {
+			return nil, err
+		}
+		s.loadSchemas()
+		return nil, s.publish(p.TextDocument.URI)
+	case "textDocument/didClose":
+		var p didCloseParams
+		if err := unmarshalParams(m, &p); err != nil {
+			return nil, err
+		}
+		delete(s.docs, p.TextDocument.URI)
+		return nil, s.conn.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+			URI:         p.TextDocument.URI,
+			Diagnostics: []diagnostic{},
+		})
+	case "textDocument/completion":
+		var p textDocumentPositionParams
+		if err := unmarshalParams(m, &p); err != nil {
+			return nil, err
+		}
+		return s.completion(p), nil
+	case "textDocument/hover":
+		var p textDocumentPositionParams
+		if err := unmarshalParams(m, &p); err != nil {
+			return nil, err
+		}
+		return s.hover(p), nil
+	case "textDocument/definition":
+		var p textDocumentPositionParams
+		if err := unmarshalParams(m, &p); err != nil {
+			return nil, err
+		}
+		return s.definition(p), nil
+	}
+
+	if m.ID == nil {
+		return nil, nil // unknown notifications are ignored
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method '%v' not found", m.Method)}
+}
+
+func unmarshalParams(m *message, v any) error {
+	if err := json.Unmarshal(m.Params, v); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *Server) initialize(p initializeParams) any {
+	s.root = p.RootPath
+	if path, ok := uriPath(p.RootURI); ok {
+		s.root = path
+	}
+	s.loadSchemas()
+
+	return map[string]any{
+		"capabilities": map[string]any{
+			"textDocumentSync": 1, // full
+			"completionProvider": map[string]any{
+				"triggerCharacters": []string{".", "{", analyze.Separator},
+			},
+			"hoverProvider":      true,
+			"definitionProvider": true,
+		},
+		"serverInfo": map[string]any{"name": "go-annotation"},
+	}
+}
+
+// loadSchemas (re)loads the schemas of the workspace; failures are logged, the server continues without schemas
+func (s *Server) loadSchemas() {
+	root := s.opts.Schemas
+	if root == "" {
+		root = s.root
+	}
+	if root == "" {
+		return
+	}
+
+	r, err := annotation.LoadSchemas(root, s.opts.Analyzer)
+	if err != nil {
+		slog.Error("failed to load schemas", "root", root, "err", err)
+		return
+	}
+	s.registry = r
+}
+
+// analysis is the result of analyzing a document
+type analysis struct {
+	path     string
+	lines    []string
+	result   *annotation.Result
+	registry *analyze.Registry
+}
+
+// analyze reads the annotations of a document, validated against the schemas of the workspace and the document
+func (s *Server) analyze(uri string) (*analysis, error) {
+	text, ok := s.docs[uri]
+	if !ok {
+		return nil, fmt.Errorf("document '%v' is not open", uri)
+	}
+
+	path, _ := uriPath(uri)
+	a := &analysis{path: path, lines: strings.Split(text, "\n")}
+
+	fset := token.NewFileSet()
+	f, _ := parser.ParseFile(fset, path, text, parser.ParseComments) // partial files are analyzed as far as possible
+	if f == nil {
+		a.registry = s.registry
+		a.result = &annotation.Result{}
+		return a, nil
+	}
+
+	types, funcs := inspect.FindFileTypes(fset, f), inspect.FindFileFunctions(fset, f)
+	local, err := annotation.ReadWithOptions(types, nil, annotation.Options{
+		Analyzer:   annotation.SchemaAnalyzer(s.opts.Analyzer),
+		Strictness: annotation.NeverFail,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	a.registry, err = annotation.ExtendSchemas(s.registry, local)
+	if err != nil {
+		slog.Warn("invalid schemas in document", "uri", uri, "err", err)
+		a.registry = s.registry
+	}
+
+	a.result, err = annotation.ReadWithOptions(types, funcs, annotation.Options{
+		Analyzer:   s.opts.Analyzer,
+		Registry:   a.registry,
+		Strictness: annotation.NeverFail,
+	})
+	return a, err
+}
+
+// publish publishes the diagnostics of a document
+func (s *Server) publish(uri string) error {
+	a, err := s.analyze(uri)
+	if err != nil {
+		return err
+	}
+
+	diagnostics := []diagnostic{}
+	for _, d := range a.result.Diagnostics {
+		line := d.Position.Line - 1
+		if line < 0 || line >= len(a.lines) {
+			continue
+		}
+
+		start := strings.Index(a.lines[line], "//")
+		if start < 0 {
+			start = 0
+		}
+
+		severity := severityWarning
+		switch d.Severity {
+		case analyze.SeverityError:
+			severity = severityError
+		case analyze.SeverityInfo:
+			severity = severityInformation
+		}
+
+		message := d.Message
+		if d.SuggestedFix != "" {
+			message = fmt.Sprintf("%s (%s)", message, d.SuggestedFix)
+		}
+
+		diagnostics = append(diagnostics, diagnostic{
+			Range: lspRange{
+				Start: position{Line: line, Character: characterOffset(a.lines[line], start)},
+				End:   position{Line: line, Character: characterOffset(a.lines[line], len(a.lines[line]))},
+			},
+			Severity: severity,
+			Code:     d.Code,
+			Source:   "annotation",
+			Message:  message,
+		})
+	}
+
+	return s.conn.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: uri, Diagnostics: diagnostics})
+}
+
+// lineAt returns the analysis of the document, the line and the byte offset of a position
+func (s *Server) lineAt(p textDocumentPositionParams) (*analysis, string, int, bool) {
+	a, err := s.analyze(p.TextDocument.URI)
+	if err != nil || p.Position.Line >= len(a.lines) {
+		return nil, "", 0, false
+	}
+
+	line := a.lines[p.Position.Line]
+	return a, line, byteOffset(line, p.Position.Character), true
+}
+
+func (s *Server) completion(p textDocumentPositionParams) []completionItem {
+	items := []completionItem{}
+	a, line, offset, ok := s.lineAt(p)
+	if !ok || a.registry == nil {
+		return items
+	}
+
+	c := completionAt(line, offset, s.opts.Analyzer.Marker)
+	switch c.kind {
+	case completeIdentifier:
+		for _, schema := range a.registry.Schemas() {
+			if strings.HasPrefix(schema.Identifier, c.prefix) {
+				items = append(items, completionItem{
+					Label:         schema.Identifier,
+					Kind:          completionKindClass,
+					Detail:        targetsDetail(schema),
+					Documentation: markdown(schemaDoc(schema)),
+				})
+			}
+		}
+	case completeKey:
+		schema, ok := a.registry.Lookup(c.identifier)
+		if !ok {
+			return items
+		}
+		for _, attr := range schema.Attributes {
+			if strings.HasPrefix(attr.Name, c.prefix) && !c.present[attr.Name] {
+				items = append(items, completionItem{
+					Label:         attr.Name,
+					Kind:          completionKindField,
+					Detail:        string(attributeType(attr)),
+					Documentation: markdown(attributeDoc(attr)),
+					InsertText:    attr.Name + "=",
+				})
+			}
+		}
+	}
+	return items
+}
+
+func (s *Server) hover(p textDocumentPositionParams) *hover {
+	a, line, offset, ok := s.lineAt(p)
+	if !ok || a.registry == nil {
+		return nil
+	}
+
+	ref, ok := referenceAt(line, offset, s.opts.Analyzer.Marker)
+	if !ok {
+		return nil
+	}
+
+	schema, ok := a.registry.Lookup(ref.identifier)
+	if !ok {
+		return nil
+	}
+
+	doc := schemaDoc(schema)
+	if ref.key != "" {
+		attr, ok := schema.Attribute(ref.key)
+		if !ok {
+			return nil
+		}
+		doc = attributeDoc(attr)
+	}
+
+	return &hover{
+		Contents: *markdown(doc),
+		Range: &lspRange{
+			Start: position{Line: p.Position.Line, Character: characterOffset(line, ref.start)},
+			End:   position{Line: p.Position.Line, Character: characterOffset(line, ref.end)},
+		},
+	}
+}
+
+func (s *Server) definition(p textDocumentPositionParams) []location {
+	locations := []location{}
+	a, line, offset, ok := s.lineAt(p)
+	if !ok || a.registry == nil {
+		return locations
+	}
+
+	ref, ok := referenceAt(line, offset, s.opts.Analyzer.Marker)
+	if !ok {
+		return locations
+	}
+
+	schema, ok := a.registry.Lookup(ref.identifier)
+	if !ok || schema.Position.File == "" || schema.Position.Line < 1 {
+		return locations
+	}
+
+	path, err := filepath.Abs(schema.Position.File)
+	if err != nil {
+		return locations
+	}
+
+	start := position{Line: schema.Position.Line - 1}
+	return append(locations, location{URI: pathURI(path), Range: lspRange{Start: start, End: start}})
+}
+
+func markdown(s string) *markupContent {
+	return &markupContent{Kind: "markdown", Value: s}
+}
+
+func attributeType(attr analyze.AttributeSchema) analyze.ValueType {
+	if attr.Type == "" {
+		return analyze.ValueString
+	}
+	return attr.Type
+}
+
+func targetsDetail(schema analyze.Schema) string {
+	var targets []string
+	for _, t := range schema.Targets {
+		targets = append(targets, string(t))
+	}
+	if len(targets) == 0 {
+		return "any target"
+	}
+	return strings.Join(targets, ", ")
+}
+
+// schemaDoc renders the documentation of a schema as markdown
+func schemaDoc(schema analyze.Schema) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s** (%s)\n", schema.Identifier, targetsDetail(schema))
+	if schema.Doc != "" {
+		fmt.Fprintf(&b, "\n%s\n", schema.Doc)
+	}
+
+	attrs := append([]analyze.AttributeSchema(nil), schema.Attributes...)
+	sort.SliceStable(attrs, func(i, j int) bool { return attrs[i].Required && !attrs[j].Required })
+	if len(attrs) > 0 {
+		b.WriteString("\n")
+	}
+	for _, attr := range attrs {
+		fmt.Fprintf(&b, "- %s\n", attributeSummary(attr))
+	}
+	return b.String()
+}
+
+// attributeDoc renders the documentation of an attribute as markdown
+func attributeDoc(attr analyze.AttributeSchema) string {
+	doc := attributeSummary(attr)
+	if attr.Doc != "" {
+		doc += "\n\n" + attr.Doc
+	}
+	return doc
+}
+
+func attributeSummary(attr analyze.AttributeSchema) string {
+	s := fmt.Sprintf("`%s` %s", attr.Name, attributeType(attr))
+	if attr.Required {
+		s += ", required"
+	}
+	if attr.Default != "" {
+		s += fmt.Sprintf(", default `%s`", attr.Default)
+	}
+	return s
+}
+
+// uriPath returns the path of a file URI
+func uriPath(uri string) (string, bool) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return "", false
+	}
+	return filepath.FromSlash(u.Path), true
+}
+
+// pathURI returns the file URI of a path
+func pathURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
@@ -0,0 +1,282 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/troublete/go-annotation/analyze"
+)
+
+const testDocument = `package demo
+
+// annotation.schema{name="crud.model",targets="type"}
+type Model struct {
+	// annotation.attr{required}
+	Name string
+}
+
+// crud.model{name="users"}
+type User struct{}
+
+// crud.model{}
+type Account struct{}
+
+type Base struct{}
+
+// crud.model{name="audits"}
+type Audit struct {
+	Base
+	ID string ` + "`json:\"x,omitempty\"`" + `
+}
+`
+
+// client drives a server through pipes
+type client struct {
+	t  *testing.T
+	w  *conn
+	r  *textproto.Reader
+	id int
+}
+
+func newClient(t *testing.T, opts Options) *client {
+	sr, cw := io.Pipe()
+	cr, sw := io.Pipe()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(sr, sw, opts)
+		sw.Close()
+	}()
+
+	t.Cleanup(func() {
+		cw.Close()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	})
+
+	return &client{t: t, w: newConn(nil, cw), r: textproto.NewReader(bufio.NewReader(cr))}
+}
+
+func (c *client) read() message {
+	c.t.Helper()
+	header, err := c.r.ReadMIMEHeader()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	length, _ := strconv.Atoi(header.Get("Content-Length"))
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.r.R, body); err != nil {
+		c.t.Fatal(err)
+	}
+
+	var m struct {
+		message
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(body, &m); err != nil {
+		c.t.Fatal(err)
+	}
+	m.message.Result = m.Result
+	return m.message
+}
+
+func (c *client) notify(method string, params any) {
+	c.t.Helper()
+	if err := c.w.notify(method, params); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// call sends a request and decodes the result of the response into v
+func (c *client) call(method string, params any, v any) *responseError {
+	c.t.Helper()
+	c.id++
+	id := json.RawMessage(strconv.Itoa(c.id))
+	p, _ := json.Marshal(params)
+	if err := c.w.write(message{ID: &id, Method: method, Params: p}); err != nil {
+		c.t.Fatal(err)
+	}
+
+	m := c.read()
+	if m.Error != nil {
+		return m.Error
+	}
+	if v != nil {
+		if err := json.Unmarshal(m.Result.(json.RawMessage), v); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+	return nil
+}
+
+// diagnostics reads the next published diagnostics
+func (c *client) diagnostics() publishDiagnosticsParams {
+	c.t.Helper()
+	m := c.read()
+	if m.Method != "textDocument/publishDiagnostics" {
+		c.t.Fatalf("expected diagnostics, got %v", m.Method)
+	}
+
+	var p publishDiagnosticsParams
+	if err := json.Unmarshal(m.Params, &p); err != nil {
+		c.t.Fatal(err)
+	}
+	return p
+}
+
+func Test_Server(t *testing.T) {
+	root, err := filepath.Abs("../example/complex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri := pathURI(filepath.Join(root, "demo.go"))
+
+	c := newClient(t, Options{Analyzer: analyze.Analyzer{Grammar: analyze.GrammarV1}})
+
+	var init struct {
+		Capabilities map[string]any `json:"capabilities"`
+	}
+	if err := c.call("initialize", initializeParams{RootURI: pathURI(root)}, &init); err != nil {
+		t.Fatal(err)
+	}
+	if init.Capabilities["hoverProvider"] != true || init.Capabilities["definitionProvider"] != true {
+		t.Errorf("unexpected capabilities %v", init.Capabilities)
+	}
+	c.notify("initialized", struct{}{})
+
+	t.Run("diagnostics", func(t *testing.T) {
+		c.notify("textDocument/didOpen", didOpenParams{TextDocument: textDocumentItem{URI: uri, Text: testDocument}})
+
+		p := c.diagnostics()
+		if p.URI != uri || len(p.Diagnostics) != 1 {
+			t.Fatalf("expected one diagnostic, got %v", p.Diagnostics)
+		}
+
+		d := p.Diagnostics[0]
+		if d.Code != analyze.CodeAttributeMissing || d.Range.Start != (position{Line: 11}) || d.Range.End.Character != 15 {
+			t.Errorf("unexpected diagnostic %v", d)
+		}
+
+		fixed := strings.Replace(testDocument, "crud.model{}", `crud.model{name="accounts"}`, 1)
+		c.notify("textDocument/didChange", didChangeParams{
+			TextDocument:   textDocumentIdentifier{URI: uri},
+			ContentChanges: []textDocumentContentChange{{Text: fixed}},
+		})
+		if p := c.diagnostics(); len(p.Diagnostics) != 0 {
+			t.Errorf("expected diagnostics to be cleared, got %v", p.Diagnostics)
+		}
+	})
+
+	t.Run("completion", func(t *testing.T) {
+		var items []completionItem
+		if err := c.call("textDocument/completion", textDocumentPositionParams{
+			TextDocument: textDocumentIdentifier{URI: uri},
+			Position:     position{Line: 8, Character: 8},
+		}, &items); err != nil {
+			t.Fatal(err)
+		}
+
+		labels := map[string]bool{}
+		for _, item := range items {
+			labels[item.Label] = true
+		}
+		if len(items) != 2 || !labels["crud.model"] || !labels["crud.field"] {
+			t.Errorf("expected identifiers of the document and the workspace, got %v", items)
+		}
+
+		c.notify("textDocument/didChange", didChangeParams{
+			TextDocument:   textDocumentIdentifier{URI: uri},
+			ContentChanges: []textDocumentContentChange{{Text: strings.Replace(testDocument, "crud.model{}", "crud.field{na", 1)}},
+		})
+		c.diagnostics()
+
+		items = nil
+		if err := c.call("textDocument/completion", textDocumentPositionParams{
+			TextDocument: textDocumentIdentifier{URI: uri},
+			Position:     position{Line: 11, Character: 16},
+		}, &items); err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != 1 || items[0].Label != "name" || items[0].InsertText != "name=" {
+			t.Errorf("expected key 'name' to be completed, got %v", items)
+		}
+	})
+
+	t.Run("hover", func(t *testing.T) {
+		var h hover
+		if err := c.call("textDocument/hover", textDocumentPositionParams{
+			TextDocument: textDocumentIdentifier{URI: uri},
+			Position:     position{Line: 8, Character: 6},
+		}, &h); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(h.Contents.Value, "**crud.model** (type)") || !strings.Contains(h.Contents.Value, "`name` string, required") {
+			t.Errorf("unexpected hover %v", h.Contents.Value)
+		}
+	})
+
+	t.Run("definition", func(t *testing.T) {
+		var locations []location
+		if err := c.call("textDocument/definition", textDocumentPositionParams{
+			TextDocument: textDocumentIdentifier{URI: uri},
+			Position:     position{Line: 11, Character: 6},
+		}, &locations); err != nil {
+			t.Fatal(err)
+		}
+		if len(locations) != 1 || locations[0].URI != pathURI(filepath.Join(root, "schema.go")) {
+			t.Errorf("expected definition in schema.go, got %v", locations)
+		}
+	})
+
+	t.Run("unknown method", func(t *testing.T) {
+		err := c.call("workspace/symbol", struct{}{}, nil)
+		if err == nil || err.Code != codeMethodNotFound {
+			t.Errorf("expected method not found, got %v", err)
+		}
+	})
+
+	c.notify("textDocument/didClose", didCloseParams{TextDocument: textDocumentIdentifier{URI: uri}})
+	if p := c.diagnostics(); len(p.Diagnostics) != 0 {
+		t.Error("expected diagnostics to be cleared on close")
+	}
+
+	if err := c.call("shutdown", nil, nil); err != nil {
+		t.Fatal(fmt.Errorf("failed to shut down: %w", err))
+	}
+	c.notify("exit", nil)
+}
+
+func Test_ServerRecover(t *testing.T) {
+	// the documents are not initialized, so opening one panics
+	s := &Server{}
+	p, _ := json.Marshal(didOpenParams{TextDocument: textDocumentItem{URI: "file:///a.go", Text: "package a"}})
+	id := json.RawMessage("1")
+
+	_, err := s.handle(&message{ID: &id, Method: "textDocument/didOpen", Params: p})
+	if re, ok := err.(*responseError); !ok || re.Code != codeInternalError {
+		t.Errorf("expected internal error, got %v", err)
+	}
+}
+
+func Test_ServeMalformedFrame(t *testing.T) {
+	in := "Content-Length: abc\r\n\r\n" +
+		"Content-Type: application/vscode-jsonrpc\r\n\r\n" +
+		"Content-Length: 33\r\n\r\n" + `{"jsonrpc":"2.0","method":"exit"}`
+
+	var out strings.Builder
+	if err := Serve(strings.NewReader(in), &out, Options{}); err != nil {
+		t.Fatalf("expected malformed frames not to end the server, got %v", err)
+	}
+	if n := strings.Count(out.String(), fmt.Sprintf(`"code":%d`, codeParseError)); n != 2 {
+		t.Errorf("expected two parse errors, got %v", out.String())
+	}
+}